@@ -5,6 +5,7 @@ package acceptance
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,10 @@ const (
 	bin = "../bin/killgrave"
 )
 
+// cfgModTime is the modification time of the configuration files, so the
+// Last-Modified headers of the responses with a body file can be asserted.
+var cfgModTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 // Test is the entry point for the acceptance tests.
 func Test(t *testing.T) {
 	// First of all, we extract the Killgrave version by running `killgrave version`.
@@ -165,9 +170,16 @@ func (rr reqRes) overrideAddress(address string) {
 func (rr reqRes) assertResponse(t *testing.T, response *http.Response) {
 	t.Helper()
 
-	// Read the response body
+	// Read the response body, decoding the gzip bodies so the expected ones can be written as text.
+	// The Go client only decodes them by itself when the request doesn't set its Accept-Encoding.
 	body, err := io.ReadAll(response.Body)
 	require.NoError(t, err)
+	if response.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		body, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	}
 
 	// Format the status line
 	statusLine := fmt.Sprintf("HTTP/%d.%d %s", response.ProtoMajor, response.ProtoMinor, response.Status)
@@ -266,6 +278,9 @@ func createTmpCfgDir(t *testing.T, tc testCase) string {
 
 		err = os.WriteFile(filePath, f.Data, os.ModePerm)
 		require.NoError(t, err)
+
+		err = os.Chtimes(filePath, cfgModTime, cfgModTime)
+		require.NoError(t, err)
 	}
 
 	// Tell the testing framework to clean up the temporary directory after the test is done.
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -27,14 +29,20 @@ const (
 	_defaultProxyMode     = killgrave.ProxyNone
 	_defaultStrictSlash   = true
 
-	_impostersFlag = "imposters"
-	_configFlag    = "config"
-	_hostFlag      = "host"
-	_portFlag      = "port"
-	_watcherFlag   = "watcher"
-	_secureFlag    = "secure"
-	_proxyModeFlag = "proxy-mode"
-	_proxyURLFlag  = "proxy-url"
+	_impostersFlag   = "imposters"
+	_configFlag      = "config"
+	_hostFlag        = "host"
+	_portFlag        = "port"
+	_watcherFlag     = "watcher"
+	_secureFlag      = "secure"
+	_proxyModeFlag   = "proxy-mode"
+	_proxyURLFlag    = "proxy-url"
+	_logLevelFlag    = "log-level"
+	_logFormatFlag   = "log-format"
+	_accessLogFlag   = "access-log"
+	_diagnosticsFlag = "diagnostics"
+	_adminHostFlag   = "admin-host"
+	_adminPortFlag   = "admin-port"
 )
 
 var (
@@ -42,7 +50,7 @@ var (
 	errGetDataFromHostFlag      = errors.New("error trying to get data from host flag")
 	errGetDataFromPortFlag      = errors.New("error trying to get data from port flag")
 	errGetDataFromSecureFlag    = errors.New("error trying to get data from secure flag")
-	errMandatoryURL             = errors.New("the field proxy-url is mandatory if you selected a proxy mode")
+	errGetDataFromWatcherFlag   = errors.New("error trying to get data from watcher flag")
 )
 
 // NewKillgraveCmd returns cobra.Command to run killgrave command
@@ -64,12 +72,12 @@ func NewKillgraveCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runHTTP(cmd, cfg)
+			return runHTTP(cfg)
 		},
 	}
 
 	rootCmd.ResetFlags()
-	rootCmd.PersistentFlags().StringP(_impostersFlag, "i", _defaultImpostersPath, "Directory where your imposters are located")
+	rootCmd.PersistentFlags().StringSliceP(_impostersFlag, "i", []string{_defaultImpostersPath}, "Directories or archives (tar, tar.gz, zip, local or remote) where your imposters are located, the later ones override the earlier ones")
 	rootCmd.PersistentFlags().StringP(_configFlag, "c", _defaultConfigFile, "Path to your configuration file")
 	rootCmd.Flags().StringP(_hostFlag, "H", _defaultHost, "Set a different host than localhost")
 	rootCmd.Flags().IntP(_portFlag, "P", _defaultPort, "Port to run the server")
@@ -77,23 +85,70 @@ func NewKillgraveCmd() *cobra.Command {
 	rootCmd.Flags().BoolP(_secureFlag, "s", false, "Run mock server using TLS (https)")
 	rootCmd.Flags().StringP(_proxyModeFlag, "m", _defaultProxyMode.String(), "Proxy mode, the options are all, missing or none")
 	rootCmd.Flags().StringP(_proxyURLFlag, "u", "", "The url where the proxy will redirect to")
+	rootCmd.Flags().String(_logLevelFlag, "info", "Log level, the options are debug, info, warn or error")
+	rootCmd.Flags().String(_logFormatFlag, killgrave.LogFormatText, "Log format, the options are text or json")
+	rootCmd.Flags().Bool(_accessLogFlag, false, "Log each one of the received requests")
+	rootCmd.Flags().Bool(_diagnosticsFlag, false, "Explain why the requests don't match any imposter")
+	rootCmd.Flags().String(_adminHostFlag, "", "Host of the admin listener, by default the same as the mock server")
+	rootCmd.Flags().Int(_adminPortFlag, 0, "Port of the admin listener (metrics), disabled by default")
 
 	rootCmd.SetVersionTemplate("Killgrave version: {{.Version}}\n")
 
 	return rootCmd
 }
 
-func runHTTP(cmd *cobra.Command, cfg killgrave.Config) error {
+func runHTTP(cfg killgrave.Config) error {
 	done := make(chan os.Signal, 1)
 	defer close(done)
 
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 
-	srv := runServer(cfg)
+	logger, err := killgrave.NewLogger(cfg.Log, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	var opts []server.ServerOpt
+	if cfg.Log.AccessLog {
+		opts = append(opts, server.WithAccessLog())
+	}
 
-	watcherFlag, _ := cmd.Flags().GetBool(_watcherFlag)
-	if watcherFlag || cfg.Watcher {
-		w, err := runWatcher(cfg, &srv)
+	if cfg.Diagnostics {
+		opts = append(opts, server.WithDiagnostics())
+	}
+
+	var metrics *server.Metrics
+	if cfg.Admin.Port != 0 {
+		metrics = server.NewMetrics()
+		admin := runAdmin(cfg, metrics, logger)
+		defer admin.Shutdown(context.TODO())
+
+		opts = append(opts, server.WithMetrics(metrics))
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		tp, err := killgrave.NewTracerProvider(context.Background(), cfg.Tracing)
+		if err != nil {
+			return err
+		}
+		defer tp.Shutdown(context.Background())
+
+		opts = append(opts, server.WithTracerProvider(tp))
+	}
+
+	oauth2Providers, err := newOAuth2Providers(cfg)
+	if err != nil {
+		return err
+	}
+
+	files := server.NewFileCache()
+	opts = append(opts, server.WithFileCache(files))
+
+	servers := runServers(cfg, logger, oauth2Providers, opts...)
+
+	if cfg.Watcher {
+		w, err := runWatcher(cfg, servers, metrics, logger, oauth2Providers, files, opts...)
 		if err != nil {
 			return err
 		}
@@ -102,15 +157,69 @@ func runHTTP(cmd *cobra.Command, cfg killgrave.Config) error {
 	}
 
 	<-done
-	if err := srv.Shutdown(); err != nil {
-		log.Fatal(err)
+	for _, srv := range servers {
+		if err := srv.Shutdown(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	return nil
 }
 
+// runServers runs each one of the configured servers, opts are the options shared by all of them
+func runServers(cfg killgrave.Config, logger *slog.Logger, oauth2Providers map[string]*server.OAuth2Provider, opts ...server.ServerOpt) []server.Server {
+	var servers []server.Server
+	for _, srvCfg := range cfg.ServerConfigs() {
+		servers = append(servers, runServer(srvCfg, logger, oauth2Providers[srvCfg.Name], opts...))
+	}
+	return servers
+}
+
+// newOAuth2Providers creates the OAuth2 providers of the servers that enable it, they're created
+// once so the tokens already issued are still valid after reloading the servers
+func newOAuth2Providers(cfg killgrave.Config) (map[string]*server.OAuth2Provider, error) {
+	providers := make(map[string]*server.OAuth2Provider)
+	for _, srvCfg := range cfg.ServerConfigs() {
+		if srvCfg.OAuth2 == nil {
+			continue
+		}
+
+		oauth2Cfg := *srvCfg.OAuth2
+		if oauth2Cfg.Issuer == "" {
+			scheme := "http"
+			if srvCfg.Secure {
+				scheme = "https"
+			}
+			oauth2Cfg.Issuer = fmt.Sprintf("%s://%s:%d", scheme, srvCfg.Host, srvCfg.Port)
+		}
+
+		provider, err := server.NewOAuth2Provider(oauth2Cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers[srvCfg.Name] = provider
+	}
+	return providers, nil
+}
+
+// runAdmin runs the admin listener shared by all the servers
+func runAdmin(cfg killgrave.Config, metrics *server.Metrics, logger *slog.Logger) *http.Server {
+	admin := &http.Server{
+		Addr:    cfg.AdminAddr(),
+		Handler: server.NewAdminHandler(metrics),
+	}
+
+	go func() {
+		logger.Info("the admin listener is on tap now", "addr", admin.Addr)
+		if err := admin.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	return admin
+}
+
 // TODO: refactor the method NewServer of the pkg server/http should be contain how to initialize the http server
-func runServer(cfg killgrave.Config) server.Server {
+func runServer(cfg killgrave.ConfigServer, logger *slog.Logger, oauth2 *server.OAuth2Provider, sharedOpts ...server.ServerOpt) server.Server {
 	router := mux.NewRouter().StrictSlash(_defaultStrictSlash)
 	httpAddr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 
@@ -119,22 +228,45 @@ func runServer(cfg killgrave.Config) server.Server {
 		Handler: handlers.CORS(server.PrepareAccessControl(cfg.CORS)...)(router),
 	}
 
-	proxyServer, err := server.NewProxy(cfg.Proxy.Url, cfg.Proxy.Mode)
+	proxyServer, err := server.NewProxy(cfg.Proxy.Url, cfg.Proxy.Mode,
+		server.WithProxyRules(cfg.Proxy.Rules...),
+		server.WithProxyTransforms(cfg.Proxy.Transforms...),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	imposterFs, err := server.NewImposterFS(cfg.ImpostersPath)
-	if err != nil {
-		log.Fatal(err)
+	var imposterFsOpts []server.ImposterFsOpt
+	if cfg.Interpolate {
+		imposterFsOpts = append(imposterFsOpts, server.WithInterpolation())
+	}
+
+	var imposterFss []server.ImposterFs
+	for _, source := range cfg.ImposterSources() {
+		imposterFs, err := server.NewImposterFSFromSource(source, imposterFsOpts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		imposterFss = append(imposterFss, imposterFs)
+	}
+
+	opts := []server.ServerOpt{
+		server.WithName(cfg.Name),
+		server.WithOverrides(imposterFss[1:]...),
+		server.WithLogger(logger.With("server", cfg.Name)),
+	}
+	if oauth2 != nil {
+		opts = append(opts, server.WithOAuth2(oauth2))
 	}
+	opts = append(opts, sharedOpts...)
 
 	s := server.NewServer(
 		router,
 		&httpServer,
 		proxyServer,
 		cfg.Secure,
-		imposterFs,
+		imposterFss[0],
+		opts...,
 	)
 	if err := s.Build(); err != nil {
 		log.Fatal(err)
@@ -144,77 +276,185 @@ func runServer(cfg killgrave.Config) server.Server {
 	return s
 }
 
-func runWatcher(cfg killgrave.Config, currentSrv *server.Server) (*watcher.Watcher, error) {
-	w, err := killgrave.InitializeWatcher(cfg.ImpostersPath)
+// runWatcher watches the imposters of all the servers, reloading all of them on each change.
+// The changed files are removed from the cache, so they're loaded again by the servers
+func runWatcher(cfg killgrave.Config, servers []server.Server, metrics *server.Metrics, logger *slog.Logger, oauth2Providers map[string]*server.OAuth2Provider, files *server.FileCache, opts ...server.ServerOpt) (*watcher.Watcher, error) {
+	w, err := killgrave.InitializeWatcher(cfg.AllImposterSources()...)
 	if err != nil {
 		return nil, err
 	}
 
-	killgrave.AttachWatcher(w, func() {
-		if err := currentSrv.Shutdown(); err != nil {
-			log.Fatal(err)
+	killgrave.AttachWatcher(w, func(evt watcher.Event) {
+		files.Invalidate(evt.Path)
+		if evt.OldPath != "" {
+			files.Invalidate(evt.OldPath)
+		}
+
+		for i, srvCfg := range cfg.ServerConfigs() {
+			if err := servers[i].Shutdown(); err != nil {
+				log.Fatal(err)
+			}
+			servers[i] = runServer(srvCfg, logger, oauth2Providers[srvCfg.Name], opts...)
+			if metrics != nil {
+				metrics.ObserveReload(srvCfg.Name)
+			}
 		}
-		*currentSrv = runServer(cfg)
 	})
 	return w, nil
 }
 
+// prepareConfig builds the configuration layering, from lowest to highest priority,
+// the defaults, the config file, the KILLGRAVE_* environment variables and the flags
+// explicitly set by the user
 func prepareConfig(cmd *cobra.Command) (killgrave.Config, error) {
-	cfgPath, _ := cmd.Flags().GetString("config")
-	if cfgPath != "" {
-		return killgrave.NewConfigFromFile(cfgPath)
+	cfg := killgrave.Config{
+		ImpostersPath: _defaultImpostersPath,
+		Host:          _defaultHost,
+		Port:          _defaultPort,
+		Proxy:         killgrave.ConfigProxy{Mode: _defaultProxyMode},
 	}
 
-	impostersPath, err := cmd.Flags().GetString("imposters")
+	cfgPath, err := cmd.Flags().GetString(_configFlag)
 	if err != nil {
-		return killgrave.Config{}, fmt.Errorf("%v: %w", err, errGetDataFromImpostersFlag)
+		return killgrave.Config{}, err
+	}
+	if envPath, ok := os.LookupEnv(killgrave.EnvConfigFile); ok && !cmd.Flags().Changed(_configFlag) {
+		cfgPath = envPath
 	}
 
-	host, err := cmd.Flags().GetString(_hostFlag)
-	if err != nil {
-		return killgrave.Config{}, fmt.Errorf("%v: %w", err, errGetDataFromHostFlag)
+	if cfgPath != "" {
+		if err := cfg.ReadFile(cfgPath); err != nil {
+			return killgrave.Config{}, err
+		}
 	}
 
-	port, err := cmd.Flags().GetInt(_portFlag)
-	if err != nil {
-		return killgrave.Config{}, fmt.Errorf("%v: %w", err, errGetDataFromPortFlag)
+	if err := cfg.ReadEnv(os.LookupEnv); err != nil {
+		return killgrave.Config{}, err
 	}
 
-	secure, err := cmd.Flags().GetBool(_secureFlag)
-	if err != nil {
-		return killgrave.Config{}, fmt.Errorf("%v: %w", err, errGetDataFromSecureFlag)
+	if err := applyFlags(cmd, &cfg); err != nil {
+		return killgrave.Config{}, err
 	}
 
-	cfg, err := killgrave.NewConfig(impostersPath, host, port, secure)
-	if err != nil {
+	if err := cfg.Validate(); err != nil {
 		return killgrave.Config{}, err
 	}
 
-	return cfg, configureProxyMode(cmd, &cfg)
+	return cfg, nil
 }
 
-func configureProxyMode(cmd *cobra.Command, cfg *killgrave.Config) error {
-	mode, err := cmd.Flags().GetString(_proxyModeFlag)
-	if err != nil {
-		return err
+// applyFlags overrides the config only with the flags that have been explicitly set
+func applyFlags(cmd *cobra.Command, cfg *killgrave.Config) error {
+	flags := cmd.Flags()
+
+	if flags.Changed(_impostersFlag) {
+		impostersPaths, err := flags.GetStringSlice(_impostersFlag)
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, errGetDataFromImpostersFlag)
+		}
+		cfg.SetImpostersPaths(impostersPaths)
 	}
 
-	pMode, err := killgrave.StringToProxyMode(mode)
-	if err != nil {
-		return err
+	if flags.Changed(_hostFlag) {
+		host, err := flags.GetString(_hostFlag)
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, errGetDataFromHostFlag)
+		}
+		cfg.Host = host
 	}
 
-	var url string
-	if mode != killgrave.ProxyNone.String() {
-		url, err = cmd.Flags().GetString(_proxyURLFlag)
+	if flags.Changed(_portFlag) {
+		port, err := flags.GetInt(_portFlag)
 		if err != nil {
+			return fmt.Errorf("%v: %w", err, errGetDataFromPortFlag)
+		}
+		cfg.Port = port
+	}
+
+	if flags.Changed(_secureFlag) {
+		secure, err := flags.GetBool(_secureFlag)
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, errGetDataFromSecureFlag)
+		}
+		cfg.Secure = secure
+	}
+
+	if flags.Changed(_logLevelFlag) {
+		var err error
+		if cfg.Log.Level, err = flags.GetString(_logLevelFlag); err != nil {
+			return err
+		}
+	}
+
+	if flags.Changed(_logFormatFlag) {
+		var err error
+		if cfg.Log.Format, err = flags.GetString(_logFormatFlag); err != nil {
 			return err
 		}
+	}
 
-		if url == "" {
-			return errMandatoryURL
+	if flags.Changed(_accessLogFlag) {
+		var err error
+		if cfg.Log.AccessLog, err = flags.GetBool(_accessLogFlag); err != nil {
+			return err
 		}
 	}
-	cfg.ConfigureProxy(pMode, url)
+
+	if flags.Changed(_adminHostFlag) {
+		var err error
+		if cfg.Admin.Host, err = flags.GetString(_adminHostFlag); err != nil {
+			return err
+		}
+	}
+
+	if flags.Changed(_adminPortFlag) {
+		var err error
+		if cfg.Admin.Port, err = flags.GetInt(_adminPortFlag); err != nil {
+			return err
+		}
+	}
+
+	if flags.Changed(_diagnosticsFlag) {
+		var err error
+		if cfg.Diagnostics, err = flags.GetBool(_diagnosticsFlag); err != nil {
+			return err
+		}
+	}
+
+	if flags.Changed(_watcherFlag) {
+		watcher, err := flags.GetBool(_watcherFlag)
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, errGetDataFromWatcherFlag)
+		}
+		cfg.Watcher = watcher
+	}
+
+	return configureProxyMode(cmd, cfg)
+}
+
+func configureProxyMode(cmd *cobra.Command, cfg *killgrave.Config) error {
+	mode := cfg.Proxy.Mode
+	if cmd.Flags().Changed(_proxyModeFlag) {
+		rawMode, err := cmd.Flags().GetString(_proxyModeFlag)
+		if err != nil {
+			return err
+		}
+
+		mode, err = killgrave.StringToProxyMode(rawMode)
+		if err != nil {
+			return err
+		}
+	}
+
+	url := cfg.Proxy.Url
+	if cmd.Flags().Changed(_proxyURLFlag) {
+		var err error
+		url, err = cmd.Flags().GetString(_proxyURLFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg.ConfigureProxy(mode, url)
 	return nil
 }
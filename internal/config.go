@@ -4,21 +4,89 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
+// Environment variables that override the values of the config file
+const (
+	EnvConfigFile      = "KILLGRAVE_CONFIG"
+	EnvImpostersPath   = "KILLGRAVE_IMPOSTERS"
+	EnvHost            = "KILLGRAVE_HOST"
+	EnvPort            = "KILLGRAVE_PORT"
+	EnvWatcher         = "KILLGRAVE_WATCHER"
+	EnvSecure          = "KILLGRAVE_SECURE"
+	EnvProxyMode       = "KILLGRAVE_PROXY_MODE"
+	EnvProxyURL        = "KILLGRAVE_PROXY_URL"
+	EnvLogLevel        = "KILLGRAVE_LOG_LEVEL"
+	EnvLogFormat       = "KILLGRAVE_LOG_FORMAT"
+	EnvAccessLog       = "KILLGRAVE_ACCESS_LOG"
+	EnvDiagnostics     = "KILLGRAVE_DIAGNOSTICS"
+	EnvAdminHost       = "KILLGRAVE_ADMIN_HOST"
+	EnvAdminPort       = "KILLGRAVE_ADMIN_PORT"
+	EnvTracingEndpoint = "KILLGRAVE_TRACING_ENDPOINT"
+	EnvTracingInsecure = "KILLGRAVE_TRACING_INSECURE"
+	EnvInterpolate     = "KILLGRAVE_INTERPOLATE_IMPOSTERS"
+)
+
 // Config representation of config file yaml
 type Config struct {
-	ImpostersPath string      `yaml:"imposters_path"`
-	Port          int         `yaml:"port"`
-	Host          string      `yaml:"host"`
-	CORS          ConfigCORS  `yaml:"cors"`
-	Proxy         ConfigProxy `yaml:"proxy"`
-	Secure        bool        `yaml:"secure"`
-	Watcher       bool        `yaml:"watcher"`
+	ImpostersPath  string         `yaml:"imposters_path"`
+	ImpostersPaths []string       `yaml:"imposters_paths"`
+	Port           int            `yaml:"port"`
+	Host           string         `yaml:"host"`
+	CORS           ConfigCORS     `yaml:"cors"`
+	Proxy          ConfigProxy    `yaml:"proxy"`
+	Secure         bool           `yaml:"secure"`
+	Watcher        bool           `yaml:"watcher"`
+	Servers        []ConfigServer `yaml:"servers"`
+	Log            ConfigLog      `yaml:"log"`
+	Diagnostics    bool           `yaml:"diagnostics"`
+	Admin          ConfigAdmin    `yaml:"admin"`
+	Tracing        ConfigTracing  `yaml:"tracing"`
+	OAuth2         *ConfigOAuth2  `yaml:"oauth2"`
+	Interpolate    bool           `yaml:"interpolate_imposters"`
+}
+
+// ConfigServer representation of each one of the named servers of the yaml,
+// all of them run in the same process sharing the watcher
+type ConfigServer struct {
+	Name           string        `yaml:"name"`
+	ImpostersPath  string        `yaml:"imposters_path"`
+	ImpostersPaths []string      `yaml:"imposters_paths"`
+	Port           int           `yaml:"port"`
+	Host           string        `yaml:"host"`
+	CORS           ConfigCORS    `yaml:"cors"`
+	Proxy          ConfigProxy   `yaml:"proxy"`
+	Secure         bool          `yaml:"secure"`
+	OAuth2         *ConfigOAuth2 `yaml:"oauth2"`
+	Interpolate    bool          `yaml:"interpolate_imposters"`
+}
+
+// ConfigOAuth2 is a representation of section oauth2 of the yaml, it enables a built-in
+// OAuth2/OIDC provider that issues signed tokens for local testing
+type ConfigOAuth2 struct {
+	Issuer         string               `yaml:"issuer"`
+	Audience       string               `yaml:"audience"`
+	TokenTTL       string               `yaml:"token_ttl"`
+	SigningKeyFile string               `yaml:"signing_key_file"`
+	Clients        []ConfigOAuth2Client `yaml:"clients"`
+	Claims         map[string]string    `yaml:"claims"`
+}
+
+// ConfigOAuth2Client is a representation of each one of the clients of the OAuth2 provider
+type ConfigOAuth2Client struct {
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
 }
 
 // ConfigCORS representation of section CORS of the yaml
@@ -32,8 +100,100 @@ type ConfigCORS struct {
 
 // ConfigProxy is a representation of section proxy of the yaml
 type ConfigProxy struct {
-	Url  string    `yaml:"url"`
-	Mode ProxyMode `yaml:"mode"`
+	Url        string                 `yaml:"url"`
+	Mode       ProxyMode              `yaml:"mode"`
+	Rules      []ConfigProxyRule      `yaml:"rules"`
+	Transforms []ConfigProxyTransform `yaml:"transforms"`
+}
+
+// ConfigProxyRule is a representation of each one of the rules of the proxy, the requests
+// that match the host and path prefix of a rule are proxied to its url instead of the default one
+type ConfigProxyRule struct {
+	Host            string            `yaml:"host"`
+	PathPrefix      string            `yaml:"path_prefix"`
+	Url             string            `yaml:"url"`
+	StripPrefix     bool              `yaml:"strip_prefix"`
+	RewritePath     *ConfigRewrite    `yaml:"rewrite_path"`
+	RequestHeaders  ConfigHeaderRules `yaml:"request_headers"`
+	ResponseHeaders ConfigHeaderRules `yaml:"response_headers"`
+	Timeout         string            `yaml:"timeout"`
+	TLS             ConfigProxyTLS    `yaml:"tls"`
+}
+
+// ConfigRewrite replaces the matches of the regular expression pattern with the replacement,
+// that can reference the captured groups as $1 or ${name}
+type ConfigRewrite struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+// ConfigHeaderRules modifies the headers of a request or a response, the headers are
+// removed first, then set (replacing the existing values) and then added
+type ConfigHeaderRules struct {
+	Remove []string          `yaml:"remove"`
+	Set    map[string]string `yaml:"set"`
+	Add    map[string]string `yaml:"add"`
+}
+
+// ConfigProxyTransform modifies the proxied responses of the requests that match its method and path,
+// an empty method or path matches any request
+type ConfigProxyTransform struct {
+	Method     string              `yaml:"method"`
+	Path       string              `yaml:"path"`
+	Status     int                 `yaml:"status"`
+	Headers    ConfigHeaderRules   `yaml:"headers"`
+	MergePatch interface{}         `yaml:"merge_patch"`
+	JSONPatch  []ConfigJSONPatchOp `yaml:"json_patch"`
+	Delay      string              `yaml:"delay"`
+	Fault      ProxyFault          `yaml:"fault"`
+}
+
+// ConfigJSONPatchOp is a representation of an operation of a JSON Patch (RFC 6902)
+type ConfigJSONPatchOp struct {
+	Op    string      `yaml:"op"`
+	Path  string      `yaml:"path"`
+	From  string      `yaml:"from"`
+	Value interface{} `yaml:"value"`
+}
+
+// ProxyFault is a failure injected on the proxied responses
+type ProxyFault string
+
+const (
+	// FaultNone returns the proxied response
+	FaultNone ProxyFault = ""
+	// FaultConnectionReset closes the connection without sending any response
+	FaultConnectionReset ProxyFault = "connection_reset"
+	// FaultEmptyBody returns the proxied response without body
+	FaultEmptyBody ProxyFault = "empty_body"
+)
+
+// ConfigProxyTLS is a representation of the TLS options used to connect with a proxied server
+type ConfigProxyTLS struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CAFile             string `yaml:"ca_file"`
+}
+
+// ConfigAdmin is a representation of section admin of the yaml, the admin
+// listener (with the metrics endpoint) is disabled when no port is configured
+type ConfigAdmin struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// ConfigTracing is a representation of section tracing of the yaml, the spans are
+// exported over OTLP/HTTP and the tracing is disabled when no endpoint is configured
+type ConfigTracing struct {
+	Endpoint    string `yaml:"endpoint"`
+	Insecure    bool   `yaml:"insecure"`
+	ServiceName string `yaml:"service_name"`
+}
+
+// ConfigLog is a representation of section log of the yaml
+type ConfigLog struct {
+	Level     string `yaml:"level"`
+	Format    string `yaml:"format"`
+	AccessLog bool   `yaml:"access_log"`
 }
 
 // ProxyMode is enumeration of proxy server modes
@@ -49,12 +209,22 @@ const (
 )
 
 var (
-	errInvalidConfigPath  = errors.New("invalid config file")
-	errEmptyImpostersPath = errors.New("imposters path can not be blank")
-	errEmptyHost          = errors.New("host can not be blank")
-	errInvalidPort        = errors.New("invalid port")
+	errInvalidConfigPath     = errors.New("invalid config file")
+	errEmptyImpostersPath    = errors.New("imposters path can not be blank")
+	errEmptyHost             = errors.New("host can not be blank")
+	errInvalidPort           = errors.New("invalid port")
+	errMandatoryProxyURL     = errors.New("the field proxy-url is mandatory if you selected a proxy mode")
+	errEmptyServerName       = errors.New("server name can not be blank")
+	errDuplicatedServer      = errors.New("duplicated server")
+	errMandatoryProxyRuleURL = errors.New("the field url is mandatory for each proxy rule")
+	errInvalidProxyFault     = errors.New("invalid proxy fault")
+	errInvalidJSONPatchOp    = errors.New("invalid json patch operation")
+	errEmptyOAuth2ClientID   = errors.New("the field client_id is mandatory for each oauth2 client")
 )
 
+// DefaultServerName is the name of the server when no named servers are configured
+const DefaultServerName = "default"
+
 func (p ProxyMode) String() string {
 	m := map[ProxyMode]string{
 		ProxyNone:    "none",
@@ -112,18 +282,6 @@ type ConfigOpt func(cfg *Config) error
 
 // NewConfig initialize the config
 func NewConfig(impostersPath, host string, port int, secure bool) (Config, error) {
-	if impostersPath == "" {
-		return Config{}, errEmptyImpostersPath
-	}
-
-	if host == "" {
-		return Config{}, errEmptyHost
-	}
-
-	if port < 0 || port > 65535 {
-		return Config{}, errInvalidPort
-	}
-
 	cfg := Config{
 		ImpostersPath: impostersPath,
 		Host:          host,
@@ -131,27 +289,527 @@ func NewConfig(impostersPath, host string, port int, secure bool) (Config, error
 		Secure:        secure,
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
 	return cfg, nil
 }
 
 // NewConfigFromFile  unmarshal content of config file to initialize a Config struct
 func NewConfigFromFile(cfgPath string) (Config, error) {
+	var cfg Config
+	if err := cfg.ReadFile(cfgPath); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// interpolateConfig resolves the references to environment variables and files of the string values of the
+// config file once it's parsed, so the values can't change the structure of the file, nor the references
+// on its comments be resolved
+func interpolateConfig(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	doc, err := interpolateConfigValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+func interpolateConfigValue(v interface{}) (interface{}, error) {
+	var err error
+	switch v := v.(type) {
+	case string:
+		interpolated, err := Interpolate([]byte(v), os.LookupEnv, nil)
+		if err != nil || string(interpolated) == v {
+			return v, err
+		}
+		return interpolatedScalar(string(interpolated)), nil
+	case []interface{}:
+		for i := range v {
+			if v[i], err = interpolateConfigValue(v[i]); err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k := range v {
+			if v[k], err = interpolateConfigValue(v[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// interpolatedScalar keeps the numbers and booleans of the interpolated values, like the port, when they're
+// written as YAML would write them, so the rest of options get the value as it is
+func interpolatedScalar(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	switch v.(type) {
+	case int, int64, uint64, float64, bool:
+		if out, err := yaml.Marshal(v); err == nil && strings.TrimSpace(string(out)) == s {
+			return v
+		}
+	}
+	return s
+}
+
+// ReadFile unmarshal content of config file over the current values of the Config,
+// so the options not present on the file keep their previous value
+func (cfg *Config) ReadFile(cfgPath string) error {
 	if cfgPath == "" {
-		return Config{}, errInvalidConfigPath
+		return errInvalidConfigPath
 	}
 	configFile, err := os.Open(cfgPath)
 	if err != nil {
-		return Config{}, fmt.Errorf("%w: error trying to read config file: %s, using default configuration instead", err, cfgPath)
+		return fmt.Errorf("%w: error trying to read config file: %s, using default configuration instead", err, cfgPath)
 	}
 	defer configFile.Close()
 
-	var cfg Config
-	bytes, _ := io.ReadAll(configFile)
-	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
-		return Config{}, fmt.Errorf("%w: error while unmarshalling configFile file %s, using default configuration instead", err, cfgPath)
+	bytes, err := io.ReadAll(configFile)
+	if err != nil {
+		return fmt.Errorf("%w: error trying to read config file: %s, using default configuration instead", err, cfgPath)
 	}
 
-	cfg.ImpostersPath = path.Join(path.Dir(cfgPath), cfg.ImpostersPath)
+	bytes, err = interpolateConfig(bytes)
+	if err != nil {
+		return fmt.Errorf("%w: error while interpolating config file %s", err, cfgPath)
+	}
 
-	return cfg, nil
+	fileCfg := *cfg
+	fileCfg.ImpostersPath = ""
+	fileCfg.ImpostersPaths = nil
+	if err := yaml.Unmarshal(bytes, &fileCfg); err != nil {
+		return fmt.Errorf("%w: error while unmarshalling configFile file %s, using default configuration instead", err, cfgPath)
+	}
+
+	if fileCfg.ImpostersPath != "" || len(fileCfg.ImpostersPaths) > 0 {
+		if fileCfg.ImpostersPath != "" {
+			fileCfg.ImpostersPath = resolveImpostersPath(cfgPath, fileCfg.ImpostersPath)
+		}
+		for i, p := range fileCfg.ImpostersPaths {
+			fileCfg.ImpostersPaths[i] = resolveImpostersPath(cfgPath, p)
+		}
+	} else {
+		fileCfg.ImpostersPath = cfg.ImpostersPath
+		fileCfg.ImpostersPaths = cfg.ImpostersPaths
+	}
+
+	resolveProxyFiles(cfgPath, &fileCfg.Proxy)
+	resolveOAuth2Files(cfgPath, fileCfg.OAuth2)
+	for i := range fileCfg.Servers {
+		srv := &fileCfg.Servers[i]
+		resolveProxyFiles(cfgPath, &srv.Proxy)
+		resolveOAuth2Files(cfgPath, srv.OAuth2)
+		if srv.ImpostersPath != "" {
+			srv.ImpostersPath = resolveImpostersPath(cfgPath, srv.ImpostersPath)
+		}
+		for j, p := range srv.ImpostersPaths {
+			srv.ImpostersPaths[j] = resolveImpostersPath(cfgPath, p)
+		}
+	}
+
+	*cfg = fileCfg
+	return nil
+}
+
+// ReadEnv overrides the values of the Config with the KILLGRAVE_* environment variables,
+// lookup is usually os.LookupEnv
+func (cfg *Config) ReadEnv(lookup func(key string) (string, bool)) error {
+	if v, ok := lookup(EnvImpostersPath); ok {
+		cfg.SetImpostersPaths(strings.Split(v, ","))
+	}
+
+	if v, ok := lookup(EnvHost); ok {
+		cfg.Host = v
+	}
+
+	if v, ok := lookup(EnvPort); ok {
+		port, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvPort)
+		}
+		cfg.Port = port
+	}
+
+	if v, ok := lookup(EnvWatcher); ok {
+		watcher, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvWatcher)
+		}
+		cfg.Watcher = watcher
+	}
+
+	if v, ok := lookup(EnvInterpolate); ok {
+		interpolate, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvInterpolate)
+		}
+		cfg.Interpolate = interpolate
+	}
+
+	if v, ok := lookup(EnvSecure); ok {
+		secure, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvSecure)
+		}
+		cfg.Secure = secure
+	}
+
+	if v, ok := lookup(EnvProxyMode); ok {
+		mode, err := StringToProxyMode(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvProxyMode)
+		}
+		cfg.Proxy.Mode = mode
+	}
+
+	if v, ok := lookup(EnvProxyURL); ok {
+		cfg.Proxy.Url = v
+	}
+
+	if v, ok := lookup(EnvLogLevel); ok {
+		cfg.Log.Level = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup(EnvLogFormat); ok {
+		cfg.Log.Format = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup(EnvAdminHost); ok {
+		cfg.Admin.Host = v
+	}
+
+	if v, ok := lookup(EnvAdminPort); ok {
+		port, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvAdminPort)
+		}
+		cfg.Admin.Port = port
+	}
+
+	if v, ok := lookup(EnvTracingEndpoint); ok {
+		cfg.Tracing.Endpoint = strings.TrimSpace(v)
+	}
+
+	if v, ok := lookup(EnvTracingInsecure); ok {
+		insecure, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvTracingInsecure)
+		}
+		cfg.Tracing.Insecure = insecure
+	}
+
+	if v, ok := lookup(EnvDiagnostics); ok {
+		diagnostics, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvDiagnostics)
+		}
+		cfg.Diagnostics = diagnostics
+	}
+
+	if v, ok := lookup(EnvAccessLog); ok {
+		accessLog, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%w: invalid value for %s", err, EnvAccessLog)
+		}
+		cfg.Log.AccessLog = accessLog
+	}
+
+	return nil
+}
+
+// ImposterSources returns the sources of imposters, ordered from lowest to highest priority.
+// When imposters_paths is defined imposters_path is ignored
+func (cfg Config) ImposterSources() []string {
+	return imposterSources(cfg.ImpostersPath, cfg.ImpostersPaths)
+}
+
+// ImposterSources returns the sources of imposters of the server, ordered from lowest to highest priority
+func (srv ConfigServer) ImposterSources() []string {
+	return imposterSources(srv.ImpostersPath, srv.ImpostersPaths)
+}
+
+// ServerConfigs returns the configuration of each one of the servers to run. If there
+// are no named servers, a single server is built from the top level options, otherwise
+// the top level options are the defaults of the servers that don't define them. The top
+// level secure and interpolate_imposters enable them on all the servers
+func (cfg Config) ServerConfigs() []ConfigServer {
+	if len(cfg.Servers) == 0 {
+		return []ConfigServer{{
+			Name:           DefaultServerName,
+			ImpostersPath:  cfg.ImpostersPath,
+			ImpostersPaths: cfg.ImpostersPaths,
+			Port:           cfg.Port,
+			Host:           cfg.Host,
+			CORS:           cfg.CORS,
+			Proxy:          cfg.Proxy,
+			Secure:         cfg.Secure,
+			OAuth2:         cfg.OAuth2,
+			Interpolate:    cfg.Interpolate,
+		}}
+	}
+
+	servers := make([]ConfigServer, 0, len(cfg.Servers))
+	for _, srv := range cfg.Servers {
+		if srv.ImpostersPath == "" && len(srv.ImpostersPaths) == 0 {
+			srv.ImpostersPath, srv.ImpostersPaths = cfg.ImpostersPath, cfg.ImpostersPaths
+		}
+
+		if srv.Host == "" {
+			srv.Host = cfg.Host
+		}
+
+		if srv.Port == 0 {
+			srv.Port = cfg.Port
+		}
+
+		if reflect.DeepEqual(srv.CORS, ConfigCORS{}) {
+			srv.CORS = cfg.CORS
+		}
+
+		if reflect.DeepEqual(srv.Proxy, ConfigProxy{}) {
+			srv.Proxy = cfg.Proxy
+		}
+
+		if srv.OAuth2 == nil {
+			srv.OAuth2 = cfg.OAuth2
+		}
+
+		srv.Secure = srv.Secure || cfg.Secure
+		srv.Interpolate = srv.Interpolate || cfg.Interpolate
+
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+// AllImposterSources returns the sources of imposters of all the servers
+func (cfg Config) AllImposterSources() []string {
+	var sources []string
+	for _, srv := range cfg.ServerConfigs() {
+		sources = append(sources, srv.ImposterSources()...)
+	}
+	return sources
+}
+
+func imposterSources(impostersPath string, impostersPaths []string) []string {
+	var sources []string
+	if len(impostersPaths) == 0 && impostersPath != "" {
+		return append(sources, impostersPath)
+	}
+
+	for _, source := range impostersPaths {
+		if source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// SetImpostersPaths replaces the sources of imposters, a single path is kept as imposters_path
+func (cfg *Config) SetImpostersPaths(paths []string) {
+	for i := range paths {
+		paths[i] = strings.TrimSpace(paths[i])
+	}
+
+	if len(paths) == 1 {
+		cfg.ImpostersPath = paths[0]
+		cfg.ImpostersPaths = nil
+		return
+	}
+
+	cfg.ImpostersPath = ""
+	cfg.ImpostersPaths = paths
+}
+
+// Validate checks that the Config is ready to be used to run the servers
+func (cfg Config) Validate() error {
+	if _, err := NewLogger(cfg.Log, io.Discard); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	addresses := make(map[string]string)
+
+	for _, srv := range cfg.ServerConfigs() {
+		if err := srv.Validate(); err != nil {
+			if len(cfg.Servers) == 0 {
+				return err
+			}
+			return fmt.Errorf("%w: server %s", err, srv.Name)
+		}
+
+		if names[srv.Name] {
+			return fmt.Errorf("%w: the name %s is already in use", errDuplicatedServer, srv.Name)
+		}
+		names[srv.Name] = true
+
+		address := fmt.Sprintf("%s:%d", srv.Host, srv.Port)
+		if name, ok := addresses[address]; ok {
+			return fmt.Errorf("%w: the servers %s and %s listen on %s", errDuplicatedServer, name, srv.Name, address)
+		}
+		addresses[address] = srv.Name
+	}
+
+	if cfg.Admin.Port < 0 || cfg.Admin.Port > 65535 {
+		return fmt.Errorf("%w: admin", errInvalidPort)
+	}
+
+	if name, ok := addresses[cfg.AdminAddr()]; ok && cfg.Admin.Port != 0 {
+		return fmt.Errorf("%w: the server %s and the admin listen on %s", errDuplicatedServer, name, cfg.AdminAddr())
+	}
+
+	return nil
+}
+
+// AdminAddr returns the address of the admin listener, by default on the top level host
+func (cfg Config) AdminAddr() string {
+	host := cfg.Admin.Host
+	if host == "" {
+		host = cfg.Host
+	}
+	return fmt.Sprintf("%s:%d", host, cfg.Admin.Port)
+}
+
+// Validate checks that the ConfigServer is ready to be used to run a server
+func (srv ConfigServer) Validate() error {
+	if srv.Name == "" {
+		return errEmptyServerName
+	}
+
+	if len(srv.ImposterSources()) == 0 {
+		return errEmptyImpostersPath
+	}
+
+	if srv.Host == "" {
+		return errEmptyHost
+	}
+
+	if srv.Port < 0 || srv.Port > 65535 {
+		return errInvalidPort
+	}
+
+	if srv.Proxy.Mode != ProxyNone && srv.Proxy.Url == "" && len(srv.Proxy.Rules) == 0 {
+		return errMandatoryProxyURL
+	}
+
+	for _, rule := range srv.Proxy.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, transform := range srv.Proxy.Transforms {
+		if err := transform.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if srv.OAuth2 != nil {
+		if err := srv.OAuth2.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the ConfigProxyRule is ready to be used by the proxy
+func (rule ConfigProxyRule) Validate() error {
+	if rule.Url == "" {
+		return fmt.Errorf("%w: host %q and path prefix %q", errMandatoryProxyRuleURL, rule.Host, rule.PathPrefix)
+	}
+
+	if rule.Timeout != "" {
+		if _, err := time.ParseDuration(rule.Timeout); err != nil {
+			return fmt.Errorf("%w: invalid timeout of the proxy rule for %s", err, rule.Url)
+		}
+	}
+
+	if rule.RewritePath != nil {
+		if _, err := regexp.Compile(rule.RewritePath.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid rewrite pattern of the proxy rule for %s", err, rule.Url)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the ConfigProxyTransform is ready to be used by the proxy
+func (t ConfigProxyTransform) Validate() error {
+	switch t.Fault {
+	case FaultNone, FaultConnectionReset, FaultEmptyBody:
+	default:
+		return fmt.Errorf("%w: %q, the options are %s or %s", errInvalidProxyFault, t.Fault, FaultConnectionReset, FaultEmptyBody)
+	}
+
+	for _, op := range t.JSONPatch {
+		switch op.Op {
+		case "add", "remove", "replace", "move", "copy", "test":
+		default:
+			return fmt.Errorf("%w: %q on the transform of %s %s", errInvalidJSONPatchOp, op.Op, t.Method, t.Path)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the ConfigOAuth2 is ready to be used by the provider
+func (o ConfigOAuth2) Validate() error {
+	if o.TokenTTL != "" {
+		if _, err := time.ParseDuration(o.TokenTTL); err != nil {
+			return fmt.Errorf("%w: invalid token_ttl of the oauth2 provider", err)
+		}
+	}
+
+	for _, client := range o.Clients {
+		if client.ClientID == "" {
+			return errEmptyOAuth2ClientID
+		}
+	}
+
+	return nil
+}
+
+// resolveProxyFiles makes the files of the proxy rules relative to the location of the config file
+func resolveProxyFiles(cfgPath string, proxy *ConfigProxy) {
+	for i := range proxy.Rules {
+		if caFile := proxy.Rules[i].TLS.CAFile; caFile != "" && !path.IsAbs(caFile) {
+			proxy.Rules[i].TLS.CAFile = path.Join(path.Dir(cfgPath), caFile)
+		}
+	}
+}
+
+// resolveOAuth2Files makes the signing key file of the OAuth2 provider relative to the location of the config file
+func resolveOAuth2Files(cfgPath string, oauth2 *ConfigOAuth2) {
+	if oauth2 != nil && oauth2.SigningKeyFile != "" && !path.IsAbs(oauth2.SigningKeyFile) {
+		oauth2.SigningKeyFile = path.Join(path.Dir(cfgPath), oauth2.SigningKeyFile)
+	}
+}
+
+// resolveImpostersPath makes the local imposters paths relative to the location of the config file
+func resolveImpostersPath(cfgPath, impostersPath string) string {
+	if u, err := url.Parse(impostersPath); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return impostersPath
+	}
+
+	if path.IsAbs(impostersPath) {
+		return impostersPath
+	}
+
+	return path.Join(path.Dir(cfgPath), impostersPath)
 }
@@ -2,9 +2,12 @@ package killgrave
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewConfigFromFile(t *testing.T) {
@@ -227,3 +230,352 @@ func TestConfig_ConfigureProxy(t *testing.T) {
 	got.ConfigureProxy(ProxyAll, "https://friendsofgo.tech")
 	assert.Equal(t, expected, got)
 }
+
+func TestConfig_ReadFile(t *testing.T) {
+	t.Run("options not present on the file keep their value", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters", Host: "localhost", Port: 3000}
+
+		err := cfg.ReadFile("test/testdata/partial_config.yml")
+		assert.NoError(t, err)
+
+		expected := Config{
+			ImpostersPath: "imposters",
+			Host:          "localhost",
+			Port:          4000,
+			Proxy: ConfigProxy{
+				Url:  "https://example.com",
+				Mode: ProxyMissing,
+			},
+		}
+		assert.Equal(t, expected, cfg)
+	})
+
+	t.Run("imposters path is relative to the config file", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters"}
+
+		err := cfg.ReadFile("test/testdata/config.yml")
+		assert.NoError(t, err)
+		assert.Equal(t, "test/testdata/imposters", cfg.ImpostersPath)
+	})
+
+	t.Run("interpolated values", func(t *testing.T) {
+		t.Setenv("KILLGRAVE_TEST_HOST", "gophers: true # not a comment")
+		t.Setenv("KILLGRAVE_TEST_PORT", "4000")
+		t.Setenv("KILLGRAVE_TEST_UPSTREAM", "example.com")
+
+		path := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(path, []byte(`# the references of the comments, like ${KILLGRAVE_TEST_MISSING:?required}, aren't resolved
+host: ${KILLGRAVE_TEST_HOST}
+port: ${KILLGRAVE_TEST_PORT}
+proxy:
+  url: https://${KILLGRAVE_TEST_UPSTREAM}/v1
+  mode: missing
+`), 0o644))
+
+		var cfg Config
+		require.NoError(t, cfg.ReadFile(path))
+		assert.Equal(t, "gophers: true # not a comment", cfg.Host)
+		assert.Equal(t, 4000, cfg.Port)
+		assert.Equal(t, ConfigProxy{Url: "https://example.com/v1", Mode: ProxyMissing}, cfg.Proxy)
+	})
+
+	t.Run("wrong yaml file keeps the previous config", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters", Port: 3000}
+
+		err := cfg.ReadFile("test/testdata/wrong_config.yml")
+		assert.Error(t, err)
+		assert.Equal(t, Config{ImpostersPath: "imposters", Port: 3000}, cfg)
+	})
+}
+
+func TestConfig_ImposterSources(t *testing.T) {
+	t.Run("single imposters path", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters"}
+		assert.Equal(t, []string{"imposters"}, cfg.ImposterSources())
+	})
+
+	t.Run("imposters paths take precedence", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters", ImpostersPaths: []string{"base", "", "overrides"}}
+		assert.Equal(t, []string{"base", "overrides"}, cfg.ImposterSources())
+	})
+
+	t.Run("paths of the config file", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters"}
+
+		err := cfg.ReadFile("test/testdata/multiple_imposters_config.yml")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"test/testdata/base",
+			"/shared/imposters.tar.gz",
+			"https://example.com/imposters.zip",
+		}, cfg.ImposterSources())
+	})
+
+	t.Run("set a single path", func(t *testing.T) {
+		cfg := Config{ImpostersPaths: []string{"base", "overrides"}}
+		cfg.SetImpostersPaths([]string{" imposters "})
+		assert.Equal(t, Config{ImpostersPath: "imposters"}, cfg)
+	})
+
+	t.Run("set several paths", func(t *testing.T) {
+		cfg := Config{ImpostersPath: "imposters"}
+		cfg.SetImpostersPaths([]string{"base", "overrides"})
+		assert.Equal(t, Config{ImpostersPaths: []string{"base", "overrides"}}, cfg)
+	})
+}
+
+func TestConfig_ServerConfigs(t *testing.T) {
+	t.Run("single server from the top level options", func(t *testing.T) {
+		cfg := validConfig()
+		assert.Equal(t, []ConfigServer{{
+			Name:          DefaultServerName,
+			ImpostersPath: cfg.ImpostersPath,
+			Port:          cfg.Port,
+			Host:          cfg.Host,
+			CORS:          cfg.CORS,
+			Secure:        cfg.Secure,
+		}}, cfg.ServerConfigs())
+	})
+
+	t.Run("named servers", func(t *testing.T) {
+		cfg, err := NewConfigFromFile("test/testdata/servers_config.yml")
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+
+		expected := []ConfigServer{
+			{
+				Name:          "users",
+				ImpostersPath: "test/testdata/users",
+				Port:          3001,
+				Host:          "0.0.0.0",
+				CORS:          ConfigCORS{Origins: []string{"*"}},
+			},
+			{
+				Name:           "payments",
+				ImpostersPaths: []string{"test/testdata/payments", "test/testdata/payments_overrides"},
+				Port:           3002,
+				Host:           "localhost",
+				CORS:           ConfigCORS{Origins: []string{"https://example.com"}},
+				Proxy:          ConfigProxy{Url: "https://payments.example.com", Mode: ProxyMissing},
+				Secure:         true,
+			},
+		}
+		assert.Equal(t, expected, cfg.ServerConfigs())
+		assert.Equal(t, []string{
+			"test/testdata/users",
+			"test/testdata/payments",
+			"test/testdata/payments_overrides",
+		}, cfg.AllImposterSources())
+	})
+
+	t.Run("top level options as defaults of the named servers", func(t *testing.T) {
+		oauth2 := &ConfigOAuth2{Clients: []ConfigOAuth2Client{{ClientID: "gopher"}}}
+		cfg := Config{
+			ImpostersPath: "imposters",
+			Port:          3000,
+			Host:          "localhost",
+			Proxy:         ConfigProxy{Url: "https://example.com", Mode: ProxyMissing},
+			Secure:        true,
+			OAuth2:        oauth2,
+			Interpolate:   true,
+			Servers: []ConfigServer{
+				{Name: "users"},
+				{
+					Name:          "payments",
+					ImpostersPath: "payments",
+					Port:          3002,
+					Proxy:         ConfigProxy{Url: "https://payments.example.com", Mode: ProxyAll},
+					OAuth2:        &ConfigOAuth2{},
+				},
+			},
+		}
+		assert.NoError(t, cfg.Validate())
+
+		assert.Equal(t, []ConfigServer{
+			{
+				Name:          "users",
+				ImpostersPath: "imposters",
+				Port:          3000,
+				Host:          "localhost",
+				Proxy:         ConfigProxy{Url: "https://example.com", Mode: ProxyMissing},
+				Secure:        true,
+				OAuth2:        oauth2,
+				Interpolate:   true,
+			},
+			{
+				Name:          "payments",
+				ImpostersPath: "payments",
+				Port:          3002,
+				Host:          "localhost",
+				Proxy:         ConfigProxy{Url: "https://payments.example.com", Mode: ProxyAll},
+				Secure:        true,
+				OAuth2:        &ConfigOAuth2{},
+				Interpolate:   true,
+			},
+		}, cfg.ServerConfigs())
+	})
+
+	t.Run("several named servers on the top level port", func(t *testing.T) {
+		cfg := Config{
+			ImpostersPath: "imposters",
+			Port:          3000,
+			Host:          "localhost",
+			Servers:       []ConfigServer{{Name: "users"}, {Name: "payments"}},
+		}
+		assert.ErrorIs(t, cfg.Validate(), errDuplicatedServer)
+	})
+
+	t.Run("invalid named servers", func(t *testing.T) {
+		testCases := map[string]struct {
+			servers []ConfigServer
+			err     error
+		}{
+			"empty name":         {servers: []ConfigServer{{ImpostersPath: "imposters", Port: 3001}}, err: errEmptyServerName},
+			"empty imposters":    {servers: []ConfigServer{{Name: "users", Port: 3001}}, err: errEmptyImpostersPath},
+			"invalid port":       {servers: []ConfigServer{{Name: "users", ImpostersPath: "imposters", Port: -1}}, err: errInvalidPort},
+			"duplicated name":    {servers: []ConfigServer{{Name: "users", ImpostersPath: "imposters", Port: 3001}, {Name: "users", ImpostersPath: "imposters", Port: 3002}}, err: errDuplicatedServer},
+			"duplicated address": {servers: []ConfigServer{{Name: "users", ImpostersPath: "imposters", Port: 3001}, {Name: "payments", ImpostersPath: "imposters", Port: 3001}}, err: errDuplicatedServer},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				cfg := Config{Host: "localhost", Servers: tc.servers}
+				assert.ErrorIs(t, cfg.Validate(), tc.err)
+			})
+		}
+	})
+}
+
+func TestConfig_ReadEnv(t *testing.T) {
+	testCases := map[string]struct {
+		env       map[string]string
+		expected  Config
+		wantError bool
+	}{
+		"no environment variables": {
+			env:      map[string]string{},
+			expected: validConfig(),
+		},
+		"override port and proxy": {
+			env: map[string]string{
+				EnvPort:      "8080",
+				EnvProxyMode: "all",
+				EnvProxyURL:  "https://friendsofgo.tech",
+			},
+			expected: func() Config {
+				cfg := validConfig()
+				cfg.Port = 8080
+				cfg.Proxy = ConfigProxy{Url: "https://friendsofgo.tech", Mode: ProxyAll}
+				return cfg
+			}(),
+		},
+		"override host, imposters and flags": {
+			env: map[string]string{
+				EnvHost:          "0.0.0.0",
+				EnvImpostersPath: "/imposters",
+				EnvWatcher:       "false",
+				EnvSecure:        "0",
+				EnvInterpolate:   "true",
+			},
+			expected: func() Config {
+				cfg := validConfig()
+				cfg.Host = "0.0.0.0"
+				cfg.ImpostersPath = "/imposters"
+				cfg.Watcher = false
+				cfg.Secure = false
+				cfg.Interpolate = true
+				return cfg
+			}(),
+		},
+		"invalid port":       {env: map[string]string{EnvPort: "http"}, expected: validConfig(), wantError: true},
+		"invalid watcher":    {env: map[string]string{EnvWatcher: "maybe"}, expected: validConfig(), wantError: true},
+		"invalid proxy mode": {env: map[string]string{EnvProxyMode: "sometimes"}, expected: validConfig(), wantError: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfg := validConfig()
+			err := cfg.ReadEnv(func(key string) (string, bool) {
+				v, ok := tc.env[key]
+				return v, ok
+			})
+
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg, err := NewConfig("imposters", "localhost", 80, false)
+	assert.NoError(t, err)
+
+	cfg.ConfigureProxy(ProxyMissing, "")
+	assert.Equal(t, errMandatoryProxyURL, cfg.Validate())
+
+	cfg.ConfigureProxy(ProxyMissing, "https://friendsofgo.tech")
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfigProxyRule_Validate(t *testing.T) {
+	testCases := map[string]struct {
+		rule    ConfigProxyRule
+		wantErr bool
+	}{
+		"valid rule":      {rule: ConfigProxyRule{PathPrefix: "/users", Url: "http://users.local", Timeout: "2s", RewritePath: &ConfigRewrite{Pattern: "^/users/(.*)$", Replacement: "/$1"}}},
+		"missing url":     {rule: ConfigProxyRule{PathPrefix: "/users"}, wantErr: true},
+		"invalid timeout": {rule: ConfigProxyRule{Url: "http://users.local", Timeout: "two seconds"}, wantErr: true},
+		"invalid rewrite": {rule: ConfigProxyRule{Url: "http://users.local", RewritePath: &ConfigRewrite{Pattern: "(users"}}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.rule.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("proxy mode with rules and without default url", func(t *testing.T) {
+		cfg, err := NewConfig("imposters", "localhost", 80, false)
+		assert.NoError(t, err)
+
+		cfg.ConfigureProxy(ProxyMissing, "")
+		cfg.Proxy.Rules = []ConfigProxyRule{{PathPrefix: "/users", Url: "http://users.local"}}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Proxy.Rules = append(cfg.Proxy.Rules, ConfigProxyRule{PathPrefix: "/orders"})
+		assert.ErrorIs(t, cfg.Validate(), errMandatoryProxyRuleURL)
+	})
+}
+
+func TestConfigProxyTransform_Validate(t *testing.T) {
+	testCases := map[string]struct {
+		transform ConfigProxyTransform
+		err       error
+	}{
+		"valid transform":   {transform: ConfigProxyTransform{Path: "/users", Status: 500, Fault: FaultEmptyBody, JSONPatch: []ConfigJSONPatchOp{{Op: "remove", Path: "/id"}}}},
+		"invalid fault":     {transform: ConfigProxyTransform{Fault: "explode"}, err: errInvalidProxyFault},
+		"invalid operation": {transform: ConfigProxyTransform{JSONPatch: []ConfigJSONPatchOp{{Op: "delete", Path: "/id"}}}, err: errInvalidJSONPatchOp},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.transform.Validate(), tc.err)
+		})
+	}
+}
+
+func TestConfigOAuth2_Validate(t *testing.T) {
+	assert.NoError(t, ConfigOAuth2{TokenTTL: "15m", Clients: []ConfigOAuth2Client{{ClientID: "gopher-app"}}}.Validate())
+	assert.Error(t, ConfigOAuth2{TokenTTL: "forever"}.Validate())
+	assert.ErrorIs(t, ConfigOAuth2{Clients: []ConfigOAuth2Client{{ClientSecret: "secret"}}}.Validate(), errEmptyOAuth2ClientID)
+}
@@ -0,0 +1,135 @@
+package killgrave
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	interpolationFilePrefix = "file:"
+	interpolationFileSuffix = "_FILE"
+)
+
+var interpolationRegexp = regexp.MustCompile(`\$\$|\$\{([^{}]*)\}`)
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Interpolate replaces the references to environment variables and files of the content.
+// The supported expressions are:
+//
+//	${VAR}              value of VAR, or the content of the file $VAR_FILE
+//	${VAR:-default}     value of VAR, or default if it is not defined or empty
+//	${VAR-default}      value of VAR, or default if it is not defined
+//	${VAR:?message}     value of VAR, or an error with the message if it is not defined or empty
+//	${file:/run/secret} content of the file, without the trailing new line
+//	$$                  a literal $
+//
+// The references that can't be resolved are left as they are. The escape function,
+// if any, is applied to each of the resolved values before replacing it.
+func Interpolate(content []byte, lookup func(key string) (string, bool), escape func(string) string) ([]byte, error) {
+	var interpolationErr error
+
+	result := interpolationRegexp.ReplaceAllFunc(content, func(match []byte) []byte {
+		if interpolationErr != nil {
+			return match
+		}
+
+		if string(match) == "$$" {
+			return []byte("$")
+		}
+
+		expr := string(match[2 : len(match)-1])
+		value, ok, err := resolveInterpolation(expr, lookup)
+		if err != nil {
+			interpolationErr = err
+			return match
+		}
+
+		if !ok {
+			return match
+		}
+
+		if escape != nil {
+			value = escape(value)
+		}
+		return []byte(value)
+	})
+
+	if interpolationErr != nil {
+		return nil, interpolationErr
+	}
+
+	return result, nil
+}
+
+func resolveInterpolation(expr string, lookup func(key string) (string, bool)) (string, bool, error) {
+	if strings.HasPrefix(expr, interpolationFilePrefix) {
+		value, err := readInterpolationFile(strings.TrimPrefix(expr, interpolationFilePrefix))
+		return value, err == nil, err
+	}
+
+	name, operator, fallback := splitInterpolation(expr)
+	if !envNameRegexp.MatchString(name) {
+		return "", false, nil
+	}
+
+	value, ok := lookupInterpolation(name, lookup)
+	if ok && (value != "" || operator == "" || operator == "-") {
+		return value, true, nil
+	}
+
+	switch operator {
+	case ":-", "-":
+		return fallback, true, nil
+	case ":?":
+		if fallback == "" {
+			fallback = "not defined"
+		}
+		return "", false, fmt.Errorf("variable %s: %s", name, fallback)
+	}
+
+	return value, ok, nil
+}
+
+func splitInterpolation(expr string) (name, operator, fallback string) {
+	idx := strings.IndexAny(expr, ":-")
+	if idx < 0 {
+		return expr, "", ""
+	}
+
+	if expr[idx] == '-' {
+		return expr[:idx], "-", expr[idx+1:]
+	}
+
+	if idx+1 < len(expr) && (expr[idx+1] == '-' || expr[idx+1] == '?') {
+		return expr[:idx], expr[idx : idx+2], expr[idx+2:]
+	}
+	return expr, "", ""
+}
+
+func lookupInterpolation(name string, lookup func(key string) (string, bool)) (string, bool) {
+	if value, ok := lookup(name); ok {
+		return value, true
+	}
+
+	filePath, ok := lookup(name + interpolationFileSuffix)
+	if !ok {
+		return "", false
+	}
+
+	value, err := readInterpolationFile(filePath)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+func readInterpolationFile(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: error trying to read the interpolated file %s", err, filePath)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,56 @@
+package killgrave
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{
+		"HOST":       "localhost",
+		"EMPTY":      "",
+		"TOKEN_FILE": "test/testdata/secrets/token",
+		"QUOTED":     `say "hi"`,
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	testCases := map[string]struct {
+		input     string
+		escape    func(string) string
+		expected  string
+		wantError bool
+	}{
+		"defined variable":                {input: "http://${HOST}:3000", expected: "http://localhost:3000"},
+		"undefined variable is kept":      {input: "${UNDEFINED}", expected: "${UNDEFINED}"},
+		"default on undefined variable":   {input: "${UNDEFINED:-8080}", expected: "8080"},
+		"default on empty variable":       {input: "${EMPTY:-8080}", expected: "8080"},
+		"dash default on empty variable":  {input: "${EMPTY-8080}", expected: ""},
+		"dash default on undefined":       {input: "${UNDEFINED-8080}", expected: "8080"},
+		"default with dashes":             {input: "${UNDEFINED:-a-b-c}", expected: "a-b-c"},
+		"required variable":               {input: "${HOST:?host is required}", expected: "localhost"},
+		"required undefined variable":     {input: "${UNDEFINED:?is required}", wantError: true},
+		"variable from _FILE":             {input: "Bearer ${TOKEN}", expected: "Bearer s3cr3t"},
+		"explicit file":                   {input: "${file:test/testdata/secrets/token}", expected: "s3cr3t"},
+		"missing explicit file":           {input: "${file:test/testdata/secrets/unknown}", wantError: true},
+		"escaped dollar":                  {input: "$${HOST} costs $$5", expected: "${HOST} costs $5"},
+		"invalid name is kept":            {input: "${a.b}", expected: "${a.b}"},
+		"escape is applied to the values": {input: `"${QUOTED}"`, escape: func(s string) string { return "<" + s + ">" }, expected: `"<say "hi">"`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Interpolate([]byte(tc.input), lookup, tc.escape)
+			if tc.wantError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(got))
+		})
+	}
+}
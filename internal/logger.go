@@ -0,0 +1,36 @@
+package killgrave
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// LogFormatText writes the logs as key=value pairs
+	LogFormatText = "text"
+	// LogFormatJSON writes the logs as JSON objects, one per line
+	LogFormatJSON = "json"
+)
+
+// NewLogger creates the logger described by the log section of the config, writing into w.
+// By default the logs are written as text with the info level
+func NewLogger(cfg ConfigLog, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("%w: unknown log level %s", err, cfg.Level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(cfg.Format) {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %s", cfg.Format)
+	}
+}
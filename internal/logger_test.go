@@ -0,0 +1,47 @@
+package killgrave
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	t.Run("default text logger with info level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := NewLogger(ConfigLog{}, &buf)
+		require.NoError(t, err)
+
+		logger.Debug("hidden")
+		logger.Info("imposter loaded", "imposter", "gophers.imp.json")
+
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), `msg="imposter loaded" imposter=gophers.imp.json`)
+	})
+
+	t.Run("json logger with debug level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := NewLogger(ConfigLog{Level: "debug", Format: "json"}, &buf)
+		require.NoError(t, err)
+
+		logger.Debug("request does not match the schema", "imposter", "gophers.imp.json")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "DEBUG", entry["level"])
+		assert.Equal(t, "gophers.imp.json", entry["imposter"])
+	})
+
+	t.Run("unknown level", func(t *testing.T) {
+		_, err := NewLogger(ConfigLog{Level: "verbose"}, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := NewLogger(ConfigLog{Format: "xml"}, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,331 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const bearerPrefix = "Bearer "
+
+var (
+	errMissingCredentials = errors.New("missing credentials")
+	errInvalidCredentials = errors.New("invalid credentials")
+	errInvalidJWTAuth     = errors.New("invalid jwt auth")
+)
+
+// RequestAuth are the credentials that the request must carry to match the imposter,
+// all the configured methods must be satisfied
+type RequestAuth struct {
+	Basic  *BasicAuth  `json:"basic,omitempty" yaml:"basic,omitempty"`
+	APIKey *APIKeyAuth `json:"apiKey,omitempty" yaml:"apiKey,omitempty"`
+	JWT    *JWTAuth    `json:"jwt,omitempty" yaml:"jwt,omitempty"`
+}
+
+// BasicAuth matches the requests with the HTTP Basic credentials
+type BasicAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// APIKeyAuth matches the requests with an API key in a header or a query parameter,
+// any of the Values, or any non empty key when there are no Values
+type APIKeyAuth struct {
+	Header string   `json:"header,omitempty" yaml:"header,omitempty"`
+	Query  string   `json:"query,omitempty" yaml:"query,omitempty"`
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// JWTAuth matches the requests with a valid bearer JWT, signed with the Secret (HMAC algorithms),
+// the public key of the PublicKeyFile or any of the keys of the JWKS url or file.
+// The expiration is always checked, and the Issuer, Audience and Claims (regular expressions
+// matched against the claims values) when they're defined
+type JWTAuth struct {
+	Header        string            `json:"header,omitempty" yaml:"header,omitempty"`
+	Secret        string            `json:"secret,omitempty" yaml:"secret,omitempty"`
+	PublicKeyFile string            `json:"publicKeyFile,omitempty" yaml:"publicKeyFile,omitempty"`
+	JWKSURL       string            `json:"jwksUrl,omitempty" yaml:"jwksUrl,omitempty"`
+	JWKSFile      string            `json:"jwksFile,omitempty" yaml:"jwksFile,omitempty"`
+	Issuer        string            `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	Audience      string            `json:"audience,omitempty" yaml:"audience,omitempty"`
+	Claims        map[string]string `json:"claims,omitempty" yaml:"claims,omitempty"`
+
+	claims map[string]*regexp.Regexp
+	keys   []jwtKey
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the jwt auth.
+func (a *JWTAuth) UnmarshalJSON(data []byte) error {
+	type jwtAuth JWTAuth
+	if err := json.Unmarshal(data, (*jwtAuth)(a)); err != nil {
+		return err
+	}
+	return a.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the jwt auth.
+func (a *JWTAuth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type jwtAuth JWTAuth
+	if err := unmarshal((*jwtAuth)(a)); err != nil {
+		return err
+	}
+	return a.validate()
+}
+
+// validate checks that there is a key to verify the tokens, and compiles the patterns of the claims
+func (a *JWTAuth) validate() error {
+	if a.Secret == "" && a.PublicKeyFile == "" && a.JWKSFile == "" && a.JWKSURL == "" {
+		return fmt.Errorf("%w: one of secret, publicKeyFile, jwksFile or jwksUrl is required", errInvalidJWTAuth)
+	}
+
+	if a.JWKSURL != "" {
+		u, err := url.Parse(a.JWKSURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: invalid jwksUrl %q", errInvalidJWTAuth, a.JWKSURL)
+		}
+	}
+
+	a.claims = make(map[string]*regexp.Regexp, len(a.Claims))
+	for name, pattern := range a.Claims {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%w: invalid pattern of the claim %s: %v", errInvalidJWTAuth, name, err)
+		}
+		a.claims[name] = re
+	}
+	return nil
+}
+
+// load resolves the keys of the secret and the files, and starts fetching the keys of the
+// jwks url, as it may be served by this same server once it's listening
+func (a *JWTAuth) load(imposter Imposter) error {
+	var keys []jwtKey
+	if a.Secret != "" {
+		keys = append(keys, jwtKey{key: []byte(a.Secret)})
+	}
+
+	if a.PublicKeyFile != "" {
+		key, err := readPublicKeyFile(imposter.CalculateFilePath(a.PublicKeyFile))
+		if err != nil {
+			return fmt.Errorf("%w: invalid public key file", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if a.JWKSFile != "" {
+		b, err := os.ReadFile(imposter.CalculateFilePath(a.JWKSFile))
+		if err != nil {
+			return fmt.Errorf("%w: invalid jwks file", err)
+		}
+		jwks, err := parseJWKS(b)
+		if err != nil {
+			return fmt.Errorf("%w: invalid jwks file", err)
+		}
+		keys = append(keys, jwks...)
+	}
+
+	if a.JWKSURL != "" {
+		go jwksKeys(a.JWKSURL)
+	}
+
+	a.keys = keys
+	return nil
+}
+
+// load resolves the keys of the jwt auth, if any
+func (a *RequestAuth) load(imposter Imposter) error {
+	if a == nil || a.JWT == nil {
+		return nil
+	}
+	return a.JWT.load(imposter)
+}
+
+// MatcherByAuth check if the request carries the credentials of the imposter
+func MatcherByAuth(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		err := validateAuth(imposter, req)
+		if err != nil {
+			loggerFromContext(req.Context()).Debug("request does not match the auth",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"error", err)
+			return false
+		}
+		return true
+	}
+}
+
+func validateAuth(imposter Imposter, req *http.Request) error {
+	auth := imposter.Request.Auth
+	if auth == nil {
+		return nil
+	}
+
+	if auth.Basic != nil {
+		if err := auth.Basic.validate(req); err != nil {
+			return fmt.Errorf("%w: basic auth", err)
+		}
+	}
+
+	if auth.APIKey != nil {
+		if err := auth.APIKey.validate(req); err != nil {
+			return fmt.Errorf("%w: api key", err)
+		}
+	}
+
+	if auth.JWT != nil {
+		if err := auth.JWT.verify(req); err != nil {
+			return fmt.Errorf("%w: jwt", err)
+		}
+	}
+
+	return nil
+}
+
+func (a BasicAuth) validate(req *http.Request) error {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return errMissingCredentials
+	}
+
+	if !secureEqual(username, a.Username) || !secureEqual(password, a.Password) {
+		return errInvalidCredentials
+	}
+	return nil
+}
+
+func (a APIKeyAuth) validate(req *http.Request) error {
+	var key string
+	switch {
+	case a.Header != "":
+		key = req.Header.Get(a.Header)
+	case a.Query != "":
+		key = req.URL.Query().Get(a.Query)
+	}
+
+	if key == "" {
+		return errMissingCredentials
+	}
+
+	if len(a.Values) == 0 {
+		return nil
+	}
+
+	for _, v := range a.Values {
+		if secureEqual(key, v) {
+			return nil
+		}
+	}
+	return errInvalidCredentials
+}
+
+func (a *JWTAuth) verify(req *http.Request) error {
+	token, err := a.token(req)
+	if err != nil {
+		return err
+	}
+
+	keys, err := a.verificationKeys()
+	if err != nil {
+		return err
+	}
+
+	claims, err := parseJWT(token, keys)
+	if err != nil {
+		return err
+	}
+
+	return a.validateClaims(claims, time.Now())
+}
+
+// token returns the token of the Authorization header, or of the configured header
+func (a JWTAuth) token(req *http.Request) (string, error) {
+	if a.Header != "" && !strings.EqualFold(a.Header, "Authorization") {
+		token := req.Header.Get(a.Header)
+		if token == "" {
+			return "", errMissingCredentials
+		}
+		return strings.TrimPrefix(token, bearerPrefix), nil
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if len(authorization) <= len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return "", errMissingCredentials
+	}
+	return authorization[len(bearerPrefix):], nil
+}
+
+// verificationKeys returns the keys resolved when the imposter was loaded and the ones of the jwks url
+func (a *JWTAuth) verificationKeys() ([]jwtKey, error) {
+	keys := a.keys
+	if a.JWKSURL != "" {
+		jwks, err := jwksKeys(a.JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys[:len(keys):len(keys)], jwks...)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no key configured to verify the token")
+	}
+	return keys, nil
+}
+
+func (a *JWTAuth) validateClaims(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return fmt.Errorf("%w: the token is expired", errInvalidCredentials)
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return fmt.Errorf("%w: the token is not valid yet", errInvalidCredentials)
+	}
+
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %v", errInvalidCredentials, claims["iss"])
+	}
+
+	if a.Audience != "" && !containsClaimValue(claims["aud"], func(v string) bool { return v == a.Audience }) {
+		return fmt.Errorf("%w: unexpected audience %v", errInvalidCredentials, claims["aud"])
+	}
+
+	for name, re := range a.claims {
+		if !containsClaimValue(claims[name], re.MatchString) {
+			return fmt.Errorf("%w: unexpected claim %s %v", errInvalidCredentials, name, claims[name])
+		}
+	}
+
+	return nil
+}
+
+// containsClaimValue checks if the value of a claim, or any of its values when it's an array, satisfies the match
+func containsClaimValue(claim interface{}, match func(string) bool) bool {
+	switch v := claim.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if containsClaimValue(item, match) {
+				return true
+			}
+		}
+		return false
+	case string:
+		return match(v)
+	}
+	return match(fmt.Sprint(claim))
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
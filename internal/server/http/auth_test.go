@@ -0,0 +1,318 @@
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMatcherByAuth(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o600))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "EC",
+		"kid": "ec-key",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwks.json"), jwks, 0o600))
+
+	valid := map[string]interface{}{
+		"iss":   "https://auth.killgrave.local",
+		"aud":   []string{"gophers", "other"},
+		"scope": "gophers:read gophers:write",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	expired := map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}
+
+	testCases := map[string]struct {
+		auth    RequestAuth
+		prepare func(r *http.Request)
+		want    bool
+	}{
+		"valid basic auth": {
+			auth:    RequestAuth{Basic: &BasicAuth{Username: "gopher", Password: "secret"}},
+			prepare: func(r *http.Request) { r.SetBasicAuth("gopher", "secret") },
+			want:    true,
+		},
+		"invalid basic auth": {
+			auth:    RequestAuth{Basic: &BasicAuth{Username: "gopher", Password: "secret"}},
+			prepare: func(r *http.Request) { r.SetBasicAuth("gopher", "wrong") },
+		},
+		"missing basic auth": {
+			auth: RequestAuth{Basic: &BasicAuth{Username: "gopher", Password: "secret"}},
+		},
+		"valid api key in header": {
+			auth:    RequestAuth{APIKey: &APIKeyAuth{Header: "X-Api-Key", Values: []string{"key1", "key2"}}},
+			prepare: func(r *http.Request) { r.Header.Set("X-Api-Key", "key2") },
+			want:    true,
+		},
+		"any api key in query": {
+			auth:    RequestAuth{APIKey: &APIKeyAuth{Query: "api_key"}},
+			prepare: func(r *http.Request) { r.URL.RawQuery = "api_key=whatever" },
+			want:    true,
+		},
+		"invalid api key": {
+			auth:    RequestAuth{APIKey: &APIKeyAuth{Header: "X-Api-Key", Values: []string{"key1"}}},
+			prepare: func(r *http.Request) { r.Header.Set("X-Api-Key", "key2") },
+		},
+		"valid jwt with secret and claims": {
+			auth: RequestAuth{JWT: &JWTAuth{
+				Secret:   "s3cr3t",
+				Issuer:   "https://auth.killgrave.local",
+				Audience: "gophers",
+				Claims:   map[string]string{"scope": `\bgophers:write\b`},
+			}},
+			prepare: bearer(hmacJWT(t, "HS256", "s3cr3t", valid)),
+			want:    true,
+		},
+		"jwt with wrong secret": {
+			auth:    RequestAuth{JWT: &JWTAuth{Secret: "s3cr3t"}},
+			prepare: bearer(hmacJWT(t, "HS256", "another", valid)),
+		},
+		"expired jwt": {
+			auth:    RequestAuth{JWT: &JWTAuth{Secret: "s3cr3t"}},
+			prepare: bearer(hmacJWT(t, "HS256", "s3cr3t", expired)),
+		},
+		"jwt with unexpected audience": {
+			auth:    RequestAuth{JWT: &JWTAuth{Secret: "s3cr3t", Audience: "payments"}},
+			prepare: bearer(hmacJWT(t, "HS256", "s3cr3t", valid)),
+		},
+		"jwt with unexpected claim": {
+			auth:    RequestAuth{JWT: &JWTAuth{Secret: "s3cr3t", Claims: map[string]string{"scope": "admin"}}},
+			prepare: bearer(hmacJWT(t, "HS256", "s3cr3t", valid)),
+		},
+		"jwt with none algorithm": {
+			auth:    RequestAuth{JWT: &JWTAuth{Secret: "s3cr3t"}},
+			prepare: bearer(encodeJWT(t, map[string]string{"alg": "none"}, valid, nil)),
+		},
+		"valid jwt with public key file": {
+			auth: RequestAuth{JWT: &JWTAuth{PublicKeyFile: "public.pem"}},
+			prepare: func(r *http.Request) {
+				token, err := signJWT(valid, rsaKey, "")
+				require.NoError(t, err)
+				bearer(token)(r)
+			},
+			want: true,
+		},
+		"hmac jwt signed with the public key": {
+			auth:    RequestAuth{JWT: &JWTAuth{PublicKeyFile: "public.pem"}},
+			prepare: bearer(hmacJWT(t, "HS256", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})), valid)),
+		},
+		"valid jwt with jwks file": {
+			auth:    RequestAuth{JWT: &JWTAuth{JWKSFile: "jwks.json", Header: "X-Token"}},
+			prepare: func(r *http.Request) { r.Header.Set("X-Token", ecdsaJWT(t, ecKey, "ec-key", valid)) },
+			want:    true,
+		},
+		"jwt with unknown kid": {
+			auth:    RequestAuth{JWT: &JWTAuth{JWKSFile: "jwks.json"}},
+			prepare: bearer(ecdsaJWT(t, ecKey, "another-key", valid)),
+		},
+		"all methods are required": {
+			auth: RequestAuth{
+				Basic:  &BasicAuth{Username: "gopher", Password: "secret"},
+				APIKey: &APIKeyAuth{Header: "X-Api-Key"},
+			},
+			prepare: func(r *http.Request) { r.SetBasicAuth("gopher", "secret") },
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			auth := tc.auth
+			imposter := Imposter{
+				BasePath: dir,
+				Request:  Request{Method: http.MethodGet, Endpoint: "/gophers", Auth: &auth},
+			}
+			if auth.JWT != nil {
+				require.NoError(t, auth.JWT.validate())
+			}
+			require.NoError(t, auth.load(imposter))
+
+			req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+			if tc.prepare != nil {
+				tc.prepare(req)
+			}
+
+			assert.Equal(t, tc.want, MatcherByAuth(imposter)(req, nil))
+		})
+	}
+}
+
+func TestJWTAuth_Unmarshal(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   bool
+	}{
+		"secret":              {input: `{"secret": "s3cr3t", "claims": {"scope": "^gophers:"}}`},
+		"jwks url":            {input: `{"jwksUrl": "http://localhost:3000/.well-known/jwks.json"}`},
+		"without keys":        {input: `{"issuer": "https://auth.killgrave.local"}`, err: true},
+		"invalid jwks url":    {input: `{"jwksUrl": "localhost:3000/jwks.json"}`, err: true},
+		"invalid claim":       {input: `{"secret": "s3cr3t", "claims": {"scope": "(gophers"}}`, err: true},
+		"invalid secret type": {input: `{"secret": ["s3cr3t"]}`, err: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var a JWTAuth
+			err := json.Unmarshal([]byte(tc.input), &a)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var a JWTAuth
+	err := yaml.Unmarshal([]byte("secret: s3cr3t\nclaims:\n  scope: \"(gophers\"\n"), &a)
+	assert.True(t, errors.Is(err, errInvalidJWTAuth), "unexpected error %v", err)
+}
+
+func TestJWTAuth_Load(t *testing.T) {
+	imposter := Imposter{BasePath: t.TempDir()}
+
+	a := JWTAuth{Secret: "s3cr3t"}
+	require.NoError(t, a.load(imposter))
+	assert.Len(t, a.keys, 1)
+
+	for _, a := range []JWTAuth{{PublicKeyFile: "public.pem"}, {JWKSFile: "jwks.json"}} {
+		assert.Error(t, a.load(imposter), "the missing key files must fail")
+	}
+}
+
+func TestJWKSKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{newRSAJWK(&key.PublicKey, "rsa-key")}})
+	}))
+	defer srv.Close()
+
+	keys, err := jwksKeys(srv.URL)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "rsa-key", keys[0].kid)
+
+	_, err = jwksKeys(srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "the cached keys must be reused")
+
+	jwksCache.Lock()
+	entry := jwksCache.entries[srv.URL]
+	entry.fetched = time.Now().Add(-jwksCacheTTL)
+	jwksCache.entries[srv.URL] = entry
+	jwksCache.Unlock()
+
+	stale, err := jwksKeys(srv.URL)
+	require.NoError(t, err)
+	assert.Len(t, stale, 1, "the stale keys are returned while they're fetched again")
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 2 }, time.Second, 10*time.Millisecond)
+
+	_, err = jwksKeys("http://127.0.0.1:1/jwks.json")
+	assert.Error(t, err)
+}
+
+func TestJWKSKeys_Failures(t *testing.T) {
+	var requests int32
+	status := int32(http.StatusInternalServerError)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{})
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := jwksKeys(srv.URL)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "the failed url isn't fetched until the retry interval")
+
+	jwksCache.Lock()
+	entry := jwksCache.entries[srv.URL]
+	entry.failed = time.Now().Add(-jwksRetryInterval)
+	jwksCache.entries[srv.URL] = entry
+	jwksCache.Unlock()
+	atomic.StoreInt32(&status, http.StatusOK)
+
+	keys, err := jwksKeys(srv.URL)
+	require.NoError(t, err)
+	assert.NotNil(t, keys)
+	assert.Empty(t, keys)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	_, err = jwksKeys(srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests), "the empty set of keys must be cached")
+}
+
+func bearer(token string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func encodeJWT(t *testing.T, header interface{}, claims interface{}, sign func(signed []byte) []byte) string {
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	var signature []byte
+	if sign != nil {
+		signature = sign([]byte(signed))
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func hmacJWT(t *testing.T, alg, secret string, claims interface{}) string {
+	return encodeJWT(t, jwtHeader{Alg: alg, Typ: "JWT"}, claims, func(signed []byte) []byte {
+		hash, err := jwtHash(alg)
+		require.NoError(t, err)
+		mac := hmac.New(hash.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	})
+}
+
+func ecdsaJWT(t *testing.T, key *ecdsa.PrivateKey, kid string, claims interface{}) string {
+	return encodeJWT(t, jwtHeader{Alg: "ES256", Kid: kid, Typ: "JWT"}, claims, func(signed []byte) []byte {
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest(crypto.SHA256, signed))
+		require.NoError(t, err)
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	})
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBufferedBodyFileSize is the size of the biggest body files that are read into memory, to be rendered
+// as templates and compressed. The bigger ones are streamed as they are
+const maxBufferedBodyFileSize = 1 << 20
+
+// throttleInterval is how often the throttled responses write a chunk of the body
+const throttleInterval = 100 * time.Millisecond
+
+var (
+	errInvalidBandwidth = errors.New("invalid bandwidth")
+	bandwidthPattern    = regexp.MustCompile(`(?i)^(\d+)\s*(B|KB|MB|GB)/s$`)
+	bandwidthUnits      = map[string]int64{"B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30}
+)
+
+// Bandwidth limits the bytes per second written on the body of a response, it's written as
+// the number of bytes per second with a unit, like 512B/s, 64KB/s or 1MB/s
+type Bandwidth int64
+
+// UnmarshalJSON of json.Unmarshaler interface.
+func (b *Bandwidth) UnmarshalJSON(data []byte) error {
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	return b.parse(input)
+}
+
+// MarshalJSON of json.Marshaler interface.
+func (b Bandwidth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface.
+func (b *Bandwidth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var input string
+	if err := unmarshal(&input); err != nil {
+		return err
+	}
+	return b.parse(input)
+}
+
+// MarshalYAML of yaml.Marshaler interface.
+func (b Bandwidth) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+func (b Bandwidth) String() string {
+	return strconv.FormatInt(int64(b), 10) + "B/s"
+}
+
+func (b *Bandwidth) parse(input string) error {
+	if input == "" {
+		*b = 0
+		return nil
+	}
+
+	m := bandwidthPattern.FindStringSubmatch(strings.TrimSpace(input))
+	if m == nil {
+		return fmt.Errorf("%w: %q, it must be like 512B/s, 64KB/s or 1MB/s", errInvalidBandwidth, input)
+	}
+
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%w: %q, it must be greater than zero", errInvalidBandwidth, input)
+	}
+	*b = Bandwidth(n * bandwidthUnits[strings.ToUpper(m[2])])
+	return nil
+}
+
+// throttledWriter writes the body of a response at the bandwidth, a chunk on each throttleInterval
+type throttledWriter struct {
+	http.ResponseWriter
+	bandwidth Bandwidth
+}
+
+// throttle wraps the writer to write at the bandwidth, if there is any
+func throttle(w http.ResponseWriter, bandwidth Bandwidth) http.ResponseWriter {
+	if bandwidth <= 0 {
+		return w
+	}
+	return throttledWriter{ResponseWriter: w, bandwidth: bandwidth}
+}
+
+func (tw throttledWriter) Write(b []byte) (int, error) {
+	chunk := max(1, int(int64(tw.bandwidth)*int64(throttleInterval)/int64(time.Second)))
+
+	written := 0
+	for len(b) > 0 {
+		start := time.Now()
+		n, err := tw.ResponseWriter.Write(b[:min(chunk, len(b))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		b = b[n:]
+		time.Sleep(time.Duration(int64(n)*int64(time.Second)/int64(tw.bandwidth)) - time.Since(start))
+	}
+	return written, nil
+}
+
+// bodyFileContent is the content of a body file to be served, the file itself or its rendered and encoded bytes
+type bodyFileContent struct {
+	content io.ReadSeeker
+	etag    string
+	modtime time.Time
+	sniff   []byte
+}
+
+// serveBodyFile writes the body file of the response, with an ETag and, if it isn't rendered from a template, its
+// Last-Modified time. The responses with status 200 support conditional and range requests. The small body files
+// are rendered, when the response is a template, and compressed, and the big ones are streamed as they are, or their
+// pre-compressed version
+func serveBodyFile(w http.ResponseWriter, r *http.Request, i Imposter, res Response, contentType string, data responseTemplateData, logger *slog.Logger) {
+	bodyFile := i.CalculateFilePath(*res.BodyFile)
+	c, err := bodyFileContentOf(w, r, i, res, bodyFile, data, logger)
+	if err != nil {
+		logger.Error("error reading the body file", "imposter", i.Path, "body_file", bodyFile, "error", err)
+		http.Error(w, fmt.Sprintf("the body file %s can't be read", *res.BodyFile), http.StatusInternalServerError)
+		return
+	}
+	defer closeContent(c.content)
+
+	writeContentType(w, res, contentType, c.sniff)
+	w.Header().Set("ETag", c.etag)
+
+	tw := throttle(w, res.Bandwidth)
+	if res.Status == http.StatusOK {
+		http.ServeContent(tw, r, "", c.modtime, c.content)
+		return
+	}
+
+	if !c.modtime.IsZero() {
+		w.Header().Set("Last-Modified", c.modtime.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(res.Status)
+	io.Copy(tw, c.content)
+}
+
+func bodyFileContentOf(w http.ResponseWriter, r *http.Request, i Imposter, res Response, bodyFile string, data responseTemplateData, logger *slog.Logger) (bodyFileContent, error) {
+	f := i.files.file(bodyFile)
+	content, err := f.open(bodyFile)
+	if err != nil {
+		return bodyFileContent{}, err
+	}
+
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(content, sniff)
+	sniff = sniff[:n]
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		closeContent(content)
+		return bodyFileContent{}, err
+	}
+
+	if path, encoding := precompressedBodyFile(i, bodyFile, r); encoding != "" {
+		if c, err := precompressedContent(w, i, path, encoding); err == nil {
+			closeContent(content)
+			c.sniff = sniff
+			return c, nil
+		}
+	}
+
+	if f.content == nil {
+		return bodyFileContent{content: content, etag: fileETag(f.info), modtime: f.info.ModTime(), sniff: sniff}, nil
+	}
+
+	rendered := string(f.content)
+	if res.Template {
+		tmpl, err := i.files.template(bodyFile)
+		if err == nil {
+			rendered, err = renderTemplate(tmpl, rendered, data)
+		}
+		if err != nil {
+			logger.Error("error rendering the body template", "imposter", i.Path, "error", err)
+		}
+	}
+
+	c := bodyFileContent{modtime: f.info.ModTime(), sniff: sniff}
+	if rendered != string(f.content) {
+		c.modtime, c.sniff = time.Time{}, []byte(rendered)
+	}
+
+	body, err := encodeBody(w, r, i, []byte(rendered))
+	if err != nil {
+		logger.Error("error compressing the body", "imposter", i.Path, "error", err)
+	}
+
+	c.content, c.etag = bytes.NewReader(body), contentETag(body)
+	return c, nil
+}
+
+// precompressedContent opens the pre-compressed version of a body file, and declares its encoding
+func precompressedContent(w http.ResponseWriter, i Imposter, path, encoding string) (bodyFileContent, error) {
+	f := i.files.file(path)
+	content, err := f.open(path)
+	if err != nil {
+		return bodyFileContent{}, err
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	writeContentEncoding(w, i.Compression, encoding)
+
+	c := bodyFileContent{content: content, etag: fileETag(f.info), modtime: f.info.ModTime()}
+	if i.Compression.fault() == CompressionFaultTruncated {
+		c.content = io.NewSectionReader(content.(io.ReaderAt), 0, f.info.Size()/2)
+		if closer, ok := content.(io.Closer); ok {
+			c.content = readSeekCloser{c.content, closer}
+		}
+	}
+	return c, nil
+}
+
+// closeContent closes the content when it's a file opened from the filesystem
+func closeContent(content io.ReadSeeker) {
+	if closer, ok := content.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+type readSeekCloser struct {
+	io.ReadSeeker
+	io.Closer
+}
+
+// fileETag identifies the version of a file by its size and modification time
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// contentETag identifies the content by its hash
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBandwidth_Unmarshal(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		want  Bandwidth
+		err   error
+	}{
+		"bytes":        {input: `"512B/s"`, want: 512},
+		"kilobytes":    {input: `"64KB/s"`, want: 64 << 10},
+		"megabytes":    {input: `"1 MB/s"`, want: 1 << 20},
+		"empty":        {input: `""`},
+		"without unit": {input: `"512"`, err: errInvalidBandwidth},
+		"zero":         {input: `"0KB/s"`, err: errInvalidBandwidth},
+		"unknown unit": {input: `"1TB/s"`, err: errInvalidBandwidth},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var b Bandwidth
+			err := json.Unmarshal([]byte(tc.input), &b)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, b)
+		})
+	}
+
+	var res Response
+	require.NoError(t, yaml.Unmarshal([]byte("status: 200\nbandwidth: 2KB/s\n"), &res))
+	assert.Equal(t, Bandwidth(2048), res.Bandwidth)
+}
+
+func TestImposterHandler_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("0123456789abcdefghij")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "download.bin"), content, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.json"), []byte(`{"id": {{ .PathParams.id }}}`), 0o644))
+
+	modtime := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "download.bin"), modtime, modtime))
+
+	download, template := "download.bin", "gopher.json"
+	newImposter := func(status int, bodyFile string) Imposter {
+		return Imposter{
+			BasePath: dir,
+			Path:     "downloads.imp.json",
+			Request:  Request{Method: http.MethodGet, Endpoint: "/downloads/{id}"},
+			Response: Responses{{Status: status, BodyFile: &bodyFile}},
+		}
+	}
+
+	serve := func(imposter Imposter, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/downloads/1", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		newTestRouter(imposter).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(newImposter(http.StatusOK, download), nil)
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, content, rec.Body.Bytes())
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Tue, 02 Jan 2024 15:04:05 GMT", rec.Header().Get("Last-Modified"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+
+	t.Run("range", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusOK, download), map[string]string{"Range": "bytes=10-14"})
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, "abcde", rec.Body.String())
+		assert.Equal(t, "bytes 10-14/20", rec.Header().Get("Content-Range"))
+	})
+
+	t.Run("unsatisfiable range", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusOK, download), map[string]string{"Range": "bytes=30-40"})
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusOK, download), map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.Bytes())
+	})
+
+	t.Run("if modified since", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusOK, download), map[string]string{"If-Modified-Since": "Wed, 03 Jan 2024 00:00:00 GMT"})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+
+		rec = serve(newImposter(http.StatusOK, download), map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 00:00:00 GMT"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("other status", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusCreated, download), map[string]string{"Range": "bytes=10-14"})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, content, rec.Body.Bytes())
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("template", func(t *testing.T) {
+		imposter := newImposter(http.StatusOK, template)
+		imposter.Response[0].Template = true
+
+		rec := serve(imposter, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"id": 1}`, rec.Body.String())
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Header().Get("Last-Modified"))
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+	})
+
+	t.Run("not a template", func(t *testing.T) {
+		rec := serve(newImposter(http.StatusOK, template), nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"id": {{ .PathParams.id }}}`, rec.Body.String())
+		assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+	})
+}
+
+func TestImposterHandler_StreamsLargeBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("{{ gopher }}"), maxBufferedBodyFileSize/10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.txt"), content, 0o644))
+
+	bodyFile := "large.txt"
+	imposter := Imposter{
+		BasePath: dir,
+		Request:  Request{Method: http.MethodGet, Endpoint: "/large"},
+		Response: Responses{{Status: http.StatusOK, BodyFile: &bodyFile}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=-12")
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "{{ gopher }}", rec.Body.String())
+}
+
+func TestFileCache_PreloadLargeTemplate(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("{{ gopher }}"), maxBufferedBodyFileSize/10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.txt"), content, 0o644))
+
+	bodyFile := "large.txt"
+	imposter := Imposter{
+		BasePath: dir,
+		Request:  Request{Method: http.MethodGet, Endpoint: "/large"},
+		Response: Responses{{Status: http.StatusOK, BodyFile: &bodyFile}},
+	}
+	assert.Empty(t, NewFileCache().preload(imposter), "the large files are streamed when the response isn't a template")
+
+	imposter.Response[0].Template = true
+	errs := NewFileCache().preload(imposter)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "without rendering it as a template")
+}
+
+func TestImposterHandler_Bandwidth(t *testing.T) {
+	imposter := Imposter{
+		Request:  Request{Method: http.MethodGet, Endpoint: "/slow"},
+		Response: Responses{{Status: http.StatusOK, Body: "0123456789abcdefghij", Bandwidth: 100}},
+	}
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, "0123456789abcdefghij", rec.Body.String())
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func newTestRouter(imposter Imposter) http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc(expandEndpoint(imposter.Request.Endpoint), ImposterHandler(imposter))
+	return router
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingGzip   = "gzip"
+	encodingBrotli = "br"
+	encodingZstd   = "zstd"
+)
+
+// defaultEncodings are the encodings used to compress the responses, in order of preference
+var defaultEncodings = []string{encodingBrotli, encodingZstd, encodingGzip}
+
+// precompressedExtensions are the extensions of the pre-compressed versions of the body files
+var precompressedExtensions = map[string]string{
+	encodingGzip:   ".gz",
+	encodingBrotli: ".br",
+	encodingZstd:   ".zst",
+}
+
+var errInvalidCompression = errors.New("invalid compression")
+
+// CompressionFault is a deliberately wrong encoding of the responses, to test how the clients handle it
+type CompressionFault string
+
+const (
+	// CompressionFaultNone encodes the responses as they're declared
+	CompressionFaultNone CompressionFault = ""
+	// CompressionFaultMismatch declares a Content-Encoding different from the one used to compress the body
+	CompressionFaultMismatch CompressionFault = "mismatch"
+	// CompressionFaultTruncated cuts the compressed body by the half
+	CompressionFaultTruncated CompressionFault = "truncated"
+	// CompressionFaultUncompressed declares a Content-Encoding but sends the body uncompressed
+	CompressionFaultUncompressed CompressionFault = "uncompressed"
+)
+
+// Compression overrides how the responses of an imposter are compressed. By default, they're compressed
+// with the encoding preferred by the Accept-Encoding header of the request, among br, zstd and gzip
+type Compression struct {
+	Disabled  bool             `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Encodings []string         `json:"encodings,omitempty" yaml:"encodings,omitempty"`
+	Fault     CompressionFault `json:"fault,omitempty" yaml:"fault,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the compression.
+func (c *Compression) UnmarshalJSON(data []byte) error {
+	type compression Compression
+	if err := json.Unmarshal(data, (*compression)(c)); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the compression.
+func (c *Compression) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type compression Compression
+	if err := unmarshal((*compression)(c)); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+func (c *Compression) validate() error {
+	for _, e := range c.Encodings {
+		if _, ok := precompressedExtensions[e]; !ok {
+			return fmt.Errorf("%w: unknown encoding %q, the options are %s, %s or %s", errInvalidCompression, e, encodingGzip, encodingBrotli, encodingZstd)
+		}
+	}
+
+	switch c.Fault {
+	case CompressionFaultNone, CompressionFaultMismatch, CompressionFaultTruncated, CompressionFaultUncompressed:
+	default:
+		return fmt.Errorf("%w: unknown fault %q, the options are %s, %s or %s", errInvalidCompression, c.Fault, CompressionFaultMismatch, CompressionFaultTruncated, CompressionFaultUncompressed)
+	}
+	return nil
+}
+
+// encodings returns the encodings that can be used, in order of preference
+func (c *Compression) encodings() []string {
+	if c == nil || len(c.Encodings) == 0 {
+		return defaultEncodings
+	}
+	return c.Encodings
+}
+
+func (c *Compression) fault() CompressionFault {
+	if c == nil {
+		return CompressionFaultNone
+	}
+	return c.Fault
+}
+
+// negotiateEncoding returns the encoding with the highest quality on the Accept-Encoding header,
+// preferring the first of the encodings on a tie, or an empty string if none is acceptable
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, e := range encodings {
+		q, ok := qualities[e]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// precompressedBodyFile returns the path of the pre-compressed version of the body file, with the encoding
+// preferred by the request, and its encoding. The body file responses/gopher.json is served as
+// responses/gopher.json.gz, responses/gopher.json.br or responses/gopher.json.zst when they exist
+func precompressedBodyFile(i Imposter, bodyFile string, r *http.Request) (string, string) {
+	if (i.Compression != nil && i.Compression.Disabled) || i.Compression.fault() == CompressionFaultUncompressed {
+		return "", ""
+	}
+
+	var available []string
+	for _, e := range i.Compression.encodings() {
+		if i.files.file(bodyFile+precompressedExtensions[e]).err == nil {
+			available = append(available, e)
+		}
+	}
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), available)
+	if encoding == "" {
+		return "", ""
+	}
+	return bodyFile + precompressedExtensions[encoding], encoding
+}
+
+// encodeBody compresses the body with the encoding preferred by the request, and writes the Content-Encoding
+// header. The body is returned as it is when the imposter disables the compression or already sets
+// a Content-Encoding header, or when it can't be compressed
+func encodeBody(w http.ResponseWriter, r *http.Request, i Imposter, body []byte) ([]byte, error) {
+	if (i.Compression != nil && i.Compression.Disabled) || len(body) == 0 || w.Header().Get("Content-Encoding") != "" {
+		return body, nil
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), i.Compression.encodings())
+	if encoding == "" {
+		return body, nil
+	}
+
+	if i.Compression.fault() == CompressionFaultUncompressed {
+		w.Header().Set("Content-Encoding", encoding)
+		return body, nil
+	}
+
+	compressed, err := compress(encoding, body)
+	if err != nil {
+		return body, err
+	}
+
+	if i.Compression.fault() == CompressionFaultTruncated {
+		compressed = compressed[:len(compressed)/2]
+	}
+
+	writeContentEncoding(w, i.Compression, encoding)
+	return compressed, nil
+}
+
+// writeContentEncoding declares the encoding of the body, or a different one with the mismatch fault
+func writeContentEncoding(w http.ResponseWriter, c *Compression, encoding string) {
+	if c.fault() == CompressionFaultMismatch {
+		encoding = otherEncoding(encoding)
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Del("Content-Length")
+}
+
+// otherEncoding returns an encoding different from the given one
+func otherEncoding(encoding string) string {
+	if encoding == encodingGzip {
+		return encodingZstd
+	}
+	return encodingGzip
+}
+
+func compress(encoding string, body []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case encodingGzip:
+		w = gzip.NewWriter(&b)
+	case encodingBrotli:
+		w = brotli.NewWriter(&b)
+	case encodingZstd:
+		zw, err := zstd.NewWriter(&b)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("%w: unknown encoding %q", errInvalidCompression, encoding)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
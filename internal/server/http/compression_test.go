@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression_Unmarshal(t *testing.T) {
+	var c Compression
+	assert.NoError(t, json.Unmarshal([]byte(`{"encodings": ["gzip", "zstd"], "fault": "truncated"}`), &c))
+
+	err := json.Unmarshal([]byte(`{"encodings": ["deflate"]}`), &Compression{})
+	assert.True(t, errors.Is(err, errInvalidCompression))
+
+	err = json.Unmarshal([]byte(`{"fault": "garbage"}`), &Compression{})
+	assert.True(t, errors.Is(err, errInvalidCompression))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := map[string]struct {
+		acceptEncoding string
+		encodings      []string
+		want           string
+	}{
+		"without header":     {encodings: defaultEncodings},
+		"single encoding":    {acceptEncoding: "gzip", encodings: defaultEncodings, want: encodingGzip},
+		"server preference":  {acceptEncoding: "gzip, deflate, br, zstd", encodings: defaultEncodings, want: encodingBrotli},
+		"client quality":     {acceptEncoding: "br;q=0.5, gzip;q=0.8", encodings: defaultEncodings, want: encodingGzip},
+		"wildcard":           {acceptEncoding: "*", encodings: []string{encodingZstd}, want: encodingZstd},
+		"rejected encoding":  {acceptEncoding: "*, br;q=0", encodings: []string{encodingBrotli, encodingGzip}, want: encodingGzip},
+		"none acceptable":    {acceptEncoding: "identity", encodings: defaultEncodings},
+		"imposter encodings": {acceptEncoding: "br, gzip", encodings: []string{encodingGzip}, want: encodingGzip},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, negotiateEncoding(tc.acceptEncoding, tc.encodings))
+		})
+	}
+}
+
+func TestImposterHandler_Compression(t *testing.T) {
+	body := `{"name": "Zebediah", "color": "Purple"}`
+
+	testCases := map[string]struct {
+		compression    *Compression
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+		decoding       string
+	}{
+		"identity":           {wantBody: body},
+		"gzip":               {acceptEncoding: "gzip", wantEncoding: encodingGzip, decoding: encodingGzip, wantBody: body},
+		"brotli":             {acceptEncoding: "gzip, br", wantEncoding: encodingBrotli, decoding: encodingBrotli, wantBody: body},
+		"zstd":               {acceptEncoding: "zstd", wantEncoding: encodingZstd, decoding: encodingZstd, wantBody: body},
+		"disabled":           {compression: &Compression{Disabled: true}, acceptEncoding: "gzip", wantBody: body},
+		"imposter encodings": {compression: &Compression{Encodings: []string{encodingZstd}}, acceptEncoding: "gzip, br, zstd", wantEncoding: encodingZstd, decoding: encodingZstd, wantBody: body},
+		"mismatch fault":     {compression: &Compression{Fault: CompressionFaultMismatch}, acceptEncoding: "gzip", wantEncoding: encodingZstd, decoding: encodingGzip, wantBody: body},
+		"uncompressed fault": {compression: &Compression{Fault: CompressionFaultUncompressed}, acceptEncoding: "gzip", wantEncoding: encodingGzip, wantBody: body},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := Imposter{
+				Request:     Request{Method: http.MethodGet, Endpoint: "/gophers/1"},
+				Response:    Responses{{Status: http.StatusOK, Body: body}},
+				Compression: tc.compression,
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/gophers/1", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, decompress(t, tc.decoding, rec.Body.Bytes()))
+		})
+	}
+}
+
+func TestImposterHandler_CompressionTruncated(t *testing.T) {
+	imposter := Imposter{
+		Request:     Request{Method: http.MethodGet, Endpoint: "/gophers/1"},
+		Response:    Responses{{Status: http.StatusOK, Body: `{"name": "Zebediah", "color": "Purple"}`}},
+		Compression: &Compression{Fault: CompressionFaultTruncated},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/gophers/1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, req)
+
+	assert.Equal(t, encodingGzip, rec.Header().Get("Content-Encoding"))
+
+	gr, err := gzip.NewReader(rec.Body)
+	if err == nil {
+		_, err = io.ReadAll(gr)
+	}
+	assert.Error(t, err)
+}
+
+func TestImposterHandler_PrecompressedBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	body := `{"name": "Zebediah"}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.json"), []byte(body), 0o644))
+
+	precompressed, err := compress(encodingBrotli, []byte(body))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.json.br"), precompressed, 0o644))
+
+	bodyFile := "gopher.json"
+	imposter := Imposter{
+		BasePath: dir,
+		Path:     "gophers.imp.json",
+		Request:  Request{Method: http.MethodGet, Endpoint: "/gophers/1"},
+		Response: Responses{{Status: http.StatusOK, BodyFile: &bodyFile}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/gophers/1", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, req)
+
+	assert.Equal(t, encodingBrotli, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, precompressed, rec.Body.Bytes())
+
+	req = httptest.NewRequest(http.MethodGet, "/gophers/1", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	rec = httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, req)
+
+	assert.Equal(t, encodingZstd, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, decompress(t, encodingZstd, rec.Body.Bytes()))
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case encodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = gr
+	case encodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case encodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
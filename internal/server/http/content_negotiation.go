@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var errInvalidResponseVariant = errors.New("invalid response variant")
+
+// ResponseVariant is a representation of the body of a response, returned when its
+// ContentType is the one preferred by the Accept header of the request
+type ResponseVariant struct {
+	ContentType string  `json:"contentType" yaml:"contentType"`
+	Body        string  `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyFile    *string `json:"bodyFile,omitempty" yaml:"bodyFile,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the variant.
+func (v *ResponseVariant) UnmarshalJSON(data []byte) error {
+	type responseVariant ResponseVariant
+	if err := json.Unmarshal(data, (*responseVariant)(v)); err != nil {
+		return err
+	}
+	return v.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the variant.
+func (v *ResponseVariant) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type responseVariant ResponseVariant
+	if err := unmarshal((*responseVariant)(v)); err != nil {
+		return err
+	}
+	return v.validate()
+}
+
+func (v *ResponseVariant) validate() error {
+	if v.ContentType == "" {
+		return fmt.Errorf("%w: the content type is mandatory", errInvalidResponseVariant)
+	}
+
+	if _, _, err := mime.ParseMediaType(v.ContentType); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidResponseVariant, err)
+	}
+	return nil
+}
+
+// acceptRange is a media range of the Accept header, like text/* or application/json;q=0.8
+type acceptRange struct {
+	mediaType string
+	subtype   string
+	q         float64
+}
+
+// parseAccept returns the media ranges of the Accept header, ignoring the invalid ones
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		mediaType, subtype, ok := strings.Cut(mediaRange, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// quality returns the quality of the content type for the media ranges, given by the most specific range that
+// matches it, and zero when there isn't any
+func quality(contentType string, ranges []acceptRange) float64 {
+	mediaRange, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0
+	}
+	mediaType, subtype, _ := strings.Cut(mediaRange, "/")
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == mediaType && r.subtype == subtype:
+			s = 2
+		case r.mediaType == mediaType && r.subtype == "*":
+			s = 1
+		case r.mediaType == "*" && r.subtype == "*":
+			s = 0
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiateResponse returns the response with the body of the variant preferred by the request, and its content type.
+// The first variant is preferred when the request hasn't an Accept header, and false is returned when none is acceptable
+func negotiateResponse(res Response, r *http.Request) (Response, string, bool) {
+	if len(res.Variants) == 0 {
+		return res, "", true
+	}
+
+	best, bestQ := 0, 1.0
+	if accept := r.Header.Get("Accept"); accept != "" {
+		ranges := parseAccept(accept)
+		best, bestQ = -1, 0
+		for i, v := range res.Variants {
+			if q := quality(v.ContentType, ranges); q > bestQ {
+				best, bestQ = i, q
+			}
+		}
+	}
+
+	if best < 0 {
+		return res, "", false
+	}
+
+	v := res.Variants[best]
+	res.Body, res.BodyFile = v.Body, v.BodyFile
+	if res.templates != nil {
+		t := *res.templates
+		t.body = t.variants[best]
+		res.templates = &t
+	}
+	return res, v.ContentType, true
+}
+
+// writeNotAcceptable responds with a 406 Not Acceptable listing the content types of the response
+func writeNotAcceptable(w http.ResponseWriter, res Response) {
+	contentTypes := make([]string, len(res.Variants))
+	for i, v := range res.Variants {
+		contentTypes[i] = v.ContentType
+	}
+
+	w.Header().Set("Vary", "Accept")
+	http.Error(w, "not acceptable, the available content types are "+strings.Join(contentTypes, ", "), http.StatusNotAcceptable)
+}
+
+// writeContentType sets the content type of the variant of the response or, when the imposter doesn't set it,
+// the one inferred from the extension of the body file or the body itself
+func writeContentType(w http.ResponseWriter, res Response, contentType string, body []byte) {
+	if len(res.Variants) > 0 {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	if contentType == "" {
+		if w.Header().Get("Content-Type") != "" {
+			return
+		}
+		contentType = inferContentType(res, body)
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+}
+
+// inferContentType returns the content type of the extension of the body file or,
+// if it's unknown, the one detected from the body
+func inferContentType(res Response, body []byte) string {
+	if res.BodyFile != nil {
+		if contentType := mime.TypeByExtension(filepath.Ext(*res.BodyFile)); contentType != "" {
+			return contentType
+		}
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "application/json"
+	}
+	return http.DetectContentType(body)
+}
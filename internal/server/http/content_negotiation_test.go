@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseVariant_Unmarshal(t *testing.T) {
+	var v ResponseVariant
+	assert.NoError(t, json.Unmarshal([]byte(`{"contentType": "text/csv", "body": "id,name"}`), &v))
+
+	err := json.Unmarshal([]byte(`{"body": "id,name"}`), &ResponseVariant{})
+	assert.True(t, errors.Is(err, errInvalidResponseVariant))
+
+	err = json.Unmarshal([]byte(`{"contentType": "text/", "body": "id,name"}`), &ResponseVariant{})
+	assert.True(t, errors.Is(err, errInvalidResponseVariant))
+}
+
+func TestImposterHandler_ContentNegotiation(t *testing.T) {
+	var variants []ResponseVariant
+	err := json.Unmarshal([]byte(`[
+		{"contentType": "application/json", "body": "{\"name\": \"Zebediah\"}"},
+		{"contentType": "application/xml", "body": "<gopher><name>Zebediah</name></gopher>"},
+		{"contentType": "text/csv", "body": "name\nZebediah"}
+	]`), &variants)
+	require.NoError(t, err)
+
+	imposter := Imposter{
+		Request:  Request{Method: http.MethodGet, Endpoint: "/gophers/1"},
+		Response: Responses{{Status: http.StatusOK, Variants: variants}},
+	}
+
+	testCases := map[string]struct {
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"without accept":         {wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `{"name": "Zebediah"}`},
+		"exact type":             {accept: "text/csv", wantStatus: http.StatusOK, wantContentType: "text/csv", wantBody: "name\nZebediah"},
+		"highest quality":        {accept: "application/json;q=0.5, application/xml;q=0.9", wantStatus: http.StatusOK, wantContentType: "application/xml", wantBody: "<gopher><name>Zebediah</name></gopher>"},
+		"subtype wildcard":       {accept: "text/*", wantStatus: http.StatusOK, wantContentType: "text/csv", wantBody: "name\nZebediah"},
+		"any type":               {accept: "*/*", wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `{"name": "Zebediah"}`},
+		"specific over wildcard": {accept: "*/*;q=0.1, application/xml;q=0", wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `{"name": "Zebediah"}`},
+		"not acceptable":         {accept: "image/png", wantStatus: http.StatusNotAcceptable, wantContentType: "text/plain; charset=utf-8", wantBody: "not acceptable, the available content types are application/json, application/xml, text/csv\n"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/gophers/1", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, req)
+
+			body, _ := io.ReadAll(rec.Body)
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+			assert.Equal(t, tc.wantBody, string(body))
+		})
+	}
+}
+
+func TestImposterHandler_InferContentType(t *testing.T) {
+	bodyFile := "test/testdata/imposters/responses/create_gopher_response.json"
+	explicit := map[string]HeaderValues{"Content-Type": {"application/vnd.api+json"}}
+
+	testCases := map[string]struct {
+		response Response
+		want     string
+	}{
+		"body file extension": {response: Response{Status: http.StatusOK, BodyFile: &bodyFile}, want: "application/json"},
+		"json body":           {response: Response{Status: http.StatusOK, Body: ` [{"name": "Zebediah"}]`}, want: "application/json"},
+		"html body":           {response: Response{Status: http.StatusOK, Body: "<html><body>Zebediah</body></html>"}, want: "text/html; charset=utf-8"},
+		"text body":           {response: Response{Status: http.StatusOK, Body: "{not json"}, want: "text/plain; charset=utf-8"},
+		"explicit header":     {response: Response{Status: http.StatusOK, Body: `{"name": "Zebediah"}`, Headers: &explicit}, want: "application/vnd.api+json"},
+		"empty body":          {response: Response{Status: http.StatusNoContent}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := Imposter{
+				Request:  Request{Method: http.MethodGet, Endpoint: "/gophers"},
+				Response: Responses{tc.response},
+			}
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers", nil))
+
+			assert.Equal(t, tc.want, rec.Header().Get("Content-Type"))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ResponseCookie is a cookie written on a Set-Cookie header of the response. Expires can be a date,
+// in RFC 3339 or HTTP format, or a duration from the moment of the response like 24h
+type ResponseCookie struct {
+	Name     string `json:"name" yaml:"name"`
+	Value    string `json:"value" yaml:"value"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	Domain   string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty" yaml:"expires,omitempty"`
+	MaxAge   int    `json:"maxAge,omitempty" yaml:"maxAge,omitempty"`
+	Secure   bool   `json:"secure,omitempty" yaml:"secure,omitempty"`
+	HttpOnly bool   `json:"httpOnly,omitempty" yaml:"httpOnly,omitempty"`
+	SameSite string `json:"sameSite,omitempty" yaml:"sameSite,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the cookie.
+func (c *ResponseCookie) UnmarshalJSON(data []byte) error {
+	type responseCookie ResponseCookie
+	if err := json.Unmarshal(data, (*responseCookie)(c)); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the cookie.
+func (c *ResponseCookie) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type responseCookie ResponseCookie
+	if err := unmarshal((*responseCookie)(c)); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+func (c *ResponseCookie) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("the name of the cookie is mandatory")
+	}
+
+	if _, err := c.sameSite(); err != nil {
+		return err
+	}
+
+	if _, err := c.expires(time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// cookie returns the http.Cookie to write on the response at the moment now
+func (c ResponseCookie) cookie(now time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		MaxAge:   c.MaxAge,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+
+	// both are already validated
+	cookie.Expires, _ = c.expires(now)
+	cookie.SameSite, _ = c.sameSite()
+	return cookie
+}
+
+func (c ResponseCookie) expires(now time.Time) (time.Time, error) {
+	if c.Expires == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(c.Expires); err == nil {
+		return now.Add(d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, http.TimeFormat} {
+		if t, err := time.Parse(layout, c.Expires); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expires %q of the cookie %s", c.Expires, c.Name)
+}
+
+func (c ResponseCookie) sameSite() (http.SameSite, error) {
+	switch strings.ToLower(c.SameSite) {
+	case "":
+		return 0, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("invalid sameSite %q of the cookie %s, the options are lax, strict or none", c.SameSite, c.Name)
+}
+
+// MatcherByCookies check if the cookies of the request matching with the imposter
+func MatcherByCookies(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		if imposter.Request.Cookies == nil {
+			return true
+		}
+
+		for name, pattern := range *imposter.Request.Cookies {
+			if !matchesCookie(req, name, pattern) {
+				loggerFromContext(req.Context()).Debug("request does not match the cookies",
+					"imposter", imposter.Path,
+					"method", imposter.Request.Method,
+					"endpoint", imposter.Request.Endpoint,
+					"cookie", name)
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// matchesCookie checks if any of the cookies with the name matches the regular expression
+func matchesCookie(r *http.Request, name string, pattern Pattern) bool {
+	return matchesAnyValue(cookieValues(r, name), pattern)
+}
+
+func cookieValues(r *http.Request, name string) []string {
+	var values []string
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			values = append(values, c.Value)
+		}
+	}
+	return values
+}
+
+func writeCookies(r Response, w http.ResponseWriter) {
+	now := time.Now()
+	for _, c := range r.Cookies {
+		http.SetCookie(w, c.cookie(now))
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcherByCookies(t *testing.T) {
+	testCases := map[string]struct {
+		cookies map[string]string
+		header  string
+		want    bool
+	}{
+		"matching cookies":     {cookies: map[string]string{"session": "^[a-f0-9]+$", "theme": ""}, header: "theme=dark; session=abc123", want: true},
+		"cookie not matching":  {cookies: map[string]string{"session": "^[a-f0-9]+$"}, header: "session=xyz"},
+		"missing cookie":       {cookies: map[string]string{"theme": ""}, header: "session=abc123"},
+		"any duplicated value": {cookies: map[string]string{"session": "^abc$"}, header: "session=xyz; session=abc", want: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := Imposter{Request: Request{Method: http.MethodGet, Endpoint: "/gophers", Cookies: patterns(t, tc.cookies)}}
+
+			req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+			req.Header.Set("Cookie", tc.header)
+
+			assert.Equal(t, tc.want, MatcherByCookies(imposter)(req, nil))
+		})
+	}
+}
+
+func TestImposterHandler_Cookies(t *testing.T) {
+	var cookies []ResponseCookie
+	err := json.Unmarshal([]byte(`[
+		{"name": "session", "value": "abc123", "path": "/", "domain": "killgrave.local", "maxAge": 3600, "httpOnly": true, "secure": true, "sameSite": "strict"},
+		{"name": "theme", "value": "dark", "expires": "2030-01-02T15:04:05Z", "sameSite": "lax"},
+		{"name": "visited", "value": "true", "expires": "24h"}
+	]`), &cookies)
+	assert.NoError(t, err)
+
+	imposter := Imposter{
+		Request:  Request{Method: http.MethodGet, Endpoint: "/gophers"},
+		Response: Responses{{Status: http.StatusOK, Cookies: cookies}},
+	}
+
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers", nil))
+
+	setCookies := rec.Header().Values("Set-Cookie")
+	assert.Len(t, setCookies, 3)
+	assert.Equal(t, "session=abc123; Path=/; Domain=killgrave.local; Max-Age=3600; HttpOnly; Secure; SameSite=Strict", setCookies[0])
+	assert.Equal(t, "theme=dark; Expires=Wed, 02 Jan 2030 15:04:05 GMT; SameSite=Lax", setCookies[1])
+
+	visited := rec.Result().Cookies()[2]
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), visited.Expires, time.Minute)
+}
+
+func TestResponseCookie_Unmarshal(t *testing.T) {
+	testCases := map[string]string{
+		"missing name":     `{"value": "abc"}`,
+		"invalid samesite": `{"name": "session", "sameSite": "sometimes"}`,
+		"invalid expires":  `{"name": "session", "expires": "tomorrow"}`,
+	}
+
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var c ResponseCookie
+			assert.Error(t, json.Unmarshal([]byte(data), &c))
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxClosestImposters is the number of imposters reported when a request doesn't match any of them
+const maxClosestImposters = 3
+
+// unmatchedResponse is the body returned, in diagnostics mode, when a request doesn't match any imposter
+type unmatchedResponse struct {
+	Error   string             `json:"error"`
+	Method  string             `json:"method"`
+	Path    string             `json:"path"`
+	Closest []imposterMismatch `json:"closest"`
+}
+
+// imposterMismatch describes the predicates of an imposter that a request doesn't satisfy
+type imposterMismatch struct {
+	Imposter string             `json:"imposter"`
+	Method   string             `json:"method"`
+	Endpoint string             `json:"endpoint"`
+	Failures []predicateFailure `json:"failures"`
+	passed   int
+}
+
+// predicateFailure describes a single predicate of an imposter that a request doesn't satisfy
+type predicateFailure struct {
+	Predicate string `json:"predicate"`
+	Name      string `json:"name,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// WithDiagnostics enables the diagnostics mode, the requests that don't match any imposter
+// get a response (and a log entry) explaining why the closest imposters didn't match
+func WithDiagnostics() ServerOpt {
+	return func(s *Server) {
+		s.diagnostics = true
+	}
+}
+
+// diagnosticsHandler returns a handler that explains why the request didn't match any imposter
+func (s *Server) diagnosticsHandler(status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		closest := closestImposters(s.imposters, r)
+
+		loggerFromContext(r.Context()).Info("no imposter matches the request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"closest", closest)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(unmatchedResponse{
+			Error:   "no imposter matches the request",
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Closest: closest,
+		})
+	}
+}
+
+// closestImposters evaluates each predicate of the imposters against the request,
+// and returns the ones that satisfy more predicates
+func closestImposters(imposters []Imposter, r *http.Request) []imposterMismatch {
+	mismatches := make([]imposterMismatch, 0, len(imposters))
+	for _, imposter := range imposters {
+		mismatches = append(mismatches, explainMismatch(imposter, r))
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		if len(mismatches[i].Failures) != len(mismatches[j].Failures) {
+			return len(mismatches[i].Failures) < len(mismatches[j].Failures)
+		}
+		return mismatches[i].passed > mismatches[j].passed
+	})
+
+	if len(mismatches) > maxClosestImposters {
+		mismatches = mismatches[:maxClosestImposters]
+	}
+	return mismatches
+}
+
+func explainMismatch(imposter Imposter, r *http.Request) imposterMismatch {
+	m := imposterMismatch{
+		Imposter: imposter.Path,
+		Method:   imposter.Request.Method,
+		Endpoint: imposter.Request.Endpoint,
+		Failures: []predicateFailure{},
+	}
+
+	check := func(ok bool, failure predicateFailure) {
+		if ok {
+			m.passed++
+			return
+		}
+		m.Failures = append(m.Failures, failure)
+	}
+
+	check(strings.EqualFold(imposter.Request.Method, r.Method), predicateFailure{
+		Predicate: "method",
+		Expected:  imposter.Request.Method,
+		Actual:    r.Method,
+	})
+
+	check(matchesRoute(mux.NewRouter().NewRoute().Path(expandEndpoint(imposter.Request.Endpoint)), r), predicateFailure{
+		Predicate: "path",
+		Expected:  imposter.Request.Endpoint,
+		Actual:    r.URL.Path,
+	})
+
+	if imposter.Request.Headers != nil {
+		for _, k := range sortedKeys(*imposter.Request.Headers) {
+			v := (*imposter.Request.Headers)[k]
+			check(matchesHeader(r, k, v), predicateFailure{
+				Predicate: "header",
+				Name:      k,
+				Expected:  v,
+				Actual:    strings.Join(r.Header.Values(k), ", "),
+			})
+		}
+	}
+
+	if imposter.Request.Params != nil {
+		for _, k := range sortedKeys(*imposter.Request.Params) {
+			v := (*imposter.Request.Params)[k]
+			check(matchesRoute(mux.NewRouter().NewRoute().Queries(k, v), r), predicateFailure{
+				Predicate: "param",
+				Name:      k,
+				Expected:  v,
+				Actual:    strings.Join(r.URL.Query()[k], ", "),
+			})
+		}
+	}
+
+	if q := imposter.Request.Query; q != nil {
+		names := make([]string, 0, len(q.Params))
+		for k := range q.Params {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			check(q.Params[k].matches(r.URL.Query()[k]), predicateFailure{
+				Predicate: "query",
+				Name:      k,
+				Expected:  q.Params[k].String(),
+				Actual:    strings.Join(r.URL.Query()[k], ", "),
+			})
+		}
+
+		for _, k := range unexpectedParams(imposter, r) {
+			check(false, predicateFailure{
+				Predicate: "query",
+				Name:      k,
+				Expected:  "absent",
+				Actual:    strings.Join(r.URL.Query()[k], ", "),
+			})
+		}
+	}
+
+	if imposter.Request.Cookies != nil {
+		for _, k := range sortedKeys(*imposter.Request.Cookies) {
+			v := (*imposter.Request.Cookies)[k]
+			check(matchesCookie(r, k, v), predicateFailure{
+				Predicate: "cookie",
+				Name:      k,
+				Expected:  v.String(),
+				Actual:    strings.Join(cookieValues(r, k), ", "),
+			})
+		}
+	}
+
+	if imposter.Request.SchemaFile != nil {
+		err := validateSchema(imposter, r)
+		failure := predicateFailure{Predicate: "schema", Expected: *imposter.Request.SchemaFile}
+		if err != nil {
+			failure.Error = err.Error()
+		}
+		check(err == nil, failure)
+	}
+
+	if imposter.Request.XSDFile != nil {
+		err := validateXSD(imposter, r)
+		failure := predicateFailure{Predicate: "xsd", Expected: *imposter.Request.XSDFile}
+		if err != nil {
+			failure.Error = err.Error()
+		}
+		check(err == nil, failure)
+	}
+
+	if imposter.Request.SOAP != nil {
+		err := validateSOAP(imposter, r)
+		failure := predicateFailure{Predicate: "soap", Expected: imposter.Request.SOAP.String()}
+		if err != nil {
+			failure.Error = err.Error()
+		}
+		check(err == nil, failure)
+	}
+
+	if imposter.Request.Form != nil || imposter.Request.Files != nil {
+		explainFormMismatch(imposter, r, check)
+	}
+
+	if imposter.Request.Auth != nil {
+		err := validateAuth(imposter, r)
+		failure := predicateFailure{Predicate: "auth"}
+		if err != nil {
+			failure.Error = err.Error()
+		}
+		check(err == nil, failure)
+	}
+
+	if len(imposter.Request.Predicates) > 0 {
+		pr := newPredicateRequest(r)
+		for _, p := range imposter.Request.Predicates {
+			check(p.evaluate(pr), predicateFailure{
+				Predicate: "predicate",
+				Expected:  p.String(),
+			})
+		}
+	}
+
+	return m
+}
+
+func explainFormMismatch(imposter Imposter, r *http.Request, check func(bool, predicateFailure)) {
+	form, err := parseRequestForm(r)
+	if err != nil {
+		check(false, predicateFailure{Predicate: "form", Error: err.Error()})
+		return
+	}
+
+	if imposter.Request.Form != nil {
+		for _, k := range sortedKeys(*imposter.Request.Form) {
+			v := (*imposter.Request.Form)[k]
+			check(matchesAnyValue(form.values[k], v), predicateFailure{
+				Predicate: "form",
+				Name:      k,
+				Expected:  v.String(),
+				Actual:    strings.Join(form.values[k], ", "),
+			})
+		}
+	}
+
+	if imposter.Request.Files != nil {
+		files := *imposter.Request.Files
+		names := make([]string, 0, len(files))
+		for k := range files {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			actual := make([]string, 0, len(form.files[k]))
+			for _, f := range form.files[k] {
+				actual = append(actual, f.String())
+			}
+
+			check(files[k].matches(form.files[k]), predicateFailure{
+				Predicate: "file",
+				Name:      k,
+				Expected:  files[k].String(),
+				Actual:    strings.Join(actual, ", "),
+			})
+		}
+	}
+}
+
+func matchesRoute(route *mux.Route, r *http.Request) bool {
+	if route.GetError() != nil {
+		return false
+	}
+	return route.Match(r, &mux.RouteMatch{})
+}
+
+// matchesHeader checks the header the same way as mux.Route.HeadersRegexp
+func matchesHeader(r *http.Request, key, pattern string) bool {
+	p, err := newPattern(pattern)
+	return err == nil && matchesAnyValue(r.Header.Values(key), p)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
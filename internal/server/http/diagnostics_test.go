@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Diagnostics(t *testing.T) {
+	imposterFs, err := NewImposterFS("test/testdata/imposters")
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	srv := NewServer(router, &http.Server{Handler: router}, &Proxy{}, false, imposterFs, WithDiagnostics())
+	require.NoError(t, srv.Build())
+
+	testCases := map[string]struct {
+		method   string
+		url      string
+		body     string
+		headers  map[string]string
+		status   int
+		imposter string
+		failures []predicateFailure
+	}{
+		"unknown path": {
+			method:   "GET",
+			url:      "/testRequests",
+			status:   http.StatusNotFound,
+			imposter: "test_request.imp.json",
+			failures: []predicateFailure{{Predicate: "path", Expected: "/testRequest", Actual: "/testRequests"}},
+		},
+		"wrong method": {
+			method:   "DELETE",
+			url:      "/testRequest",
+			status:   http.StatusMethodNotAllowed,
+			imposter: "test_request.imp.json",
+			failures: []predicateFailure{{Predicate: "method", Expected: "GET", Actual: "DELETE"}},
+		},
+		"missing header and param": {
+			method:   "POST",
+			url:      "/gophers",
+			body:     `{"data": {"type": "gophers", "attributes": {"name": "Zebediah", "color": "Purple", "age": 55}}}`,
+			status:   http.StatusNotFound,
+			imposter: "create_gopher.imp.json",
+			failures: []predicateFailure{
+				{Predicate: "header", Name: "Content-Type", Expected: "application/json"},
+				{Predicate: "param", Name: "gopherColor", Expected: "{v:[a-z]+}"},
+			},
+		},
+		"invalid schema": {
+			method:   "POST",
+			url:      "/gophers?gopherColor=purple",
+			body:     `{"data": {"type": "gophers"}}`,
+			headers:  map[string]string{"Content-Type": "application/json"},
+			status:   http.StatusNotFound,
+			imposter: "create_gopher.imp.json",
+			failures: []predicateFailure{{
+				Predicate: "schema",
+				Expected:  "schemas/create_gopher_request.json",
+				Error:     "data: attributes is required",
+			}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var res unmatchedResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tc.method, res.Method)
+			require.NotEmpty(t, res.Closest)
+			assert.LessOrEqual(t, len(res.Closest), maxClosestImposters)
+			assert.Equal(t, tc.imposter, res.Closest[0].Imposter)
+			assert.Equal(t, tc.failures, res.Closest[0].Failures)
+		})
+	}
+}
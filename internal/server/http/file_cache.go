@@ -0,0 +1,430 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// FileCache keeps in memory the body files, their parsed templates and the compiled JSON schemas and XSDs of the
+// imposters, so the requests don't hit the filesystem. It's created once and shared by the servers, the watcher
+// invalidates the files that change so they're loaded again when the servers are reloaded
+type FileCache struct {
+	mu        sync.RWMutex
+	files     map[string]cachedFile
+	templates map[string]cachedTemplate
+	schemas   map[string]cachedSchema
+	xsds      map[string]cachedXSD
+}
+
+// cachedFile is a file loaded from the filesystem, its content is only kept when it's small enough
+// to be buffered, the bigger ones are opened on each request to be streamed
+type cachedFile struct {
+	content []byte
+	info    os.FileInfo
+	err     error
+}
+
+// cachedTemplate is a body file parsed as a template, without template when the file has no actions or
+// is too big to be buffered
+type cachedTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// cachedSchema is a compiled schema, with the schema files it refers to with $ref
+type cachedSchema struct {
+	schema *gojsonschema.Schema
+	refs   []string
+	err    error
+}
+
+// cachedXSD is a compiled XSD, with the schema files it includes or imports
+type cachedXSD struct {
+	schema *xsdSchema
+	refs   []string
+	err    error
+}
+
+// NewFileCache creates an empty cache
+func NewFileCache() *FileCache {
+	return &FileCache{
+		files:     make(map[string]cachedFile),
+		templates: make(map[string]cachedTemplate),
+		schemas:   make(map[string]cachedSchema),
+		xsds:      make(map[string]cachedXSD),
+	}
+}
+
+// WithFileCache shares the cache between the servers, so it survives the reloads of the watcher
+func WithFileCache(c *FileCache) ServerOpt {
+	return func(s *Server) {
+		s.files = c
+	}
+}
+
+// Invalidate removes the file, or all the files of the directory, so they're loaded again on the next use
+func (c *FileCache) Invalidate(path string) {
+	key := cacheKey(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.files {
+		if affectedBy(k, nil, key) {
+			delete(c.files, k)
+		}
+	}
+	for k := range c.templates {
+		if affectedBy(k, nil, key) {
+			delete(c.templates, k)
+		}
+	}
+	for k, s := range c.schemas {
+		if affectedBy(k, s.refs, key) {
+			delete(c.schemas, k)
+		}
+	}
+	for k, s := range c.xsds {
+		if affectedBy(k, s.refs, key) {
+			delete(c.xsds, k)
+		}
+	}
+}
+
+// affectedBy reports whether the change of the file or directory key affects the file k, or any of the files it refers to
+func affectedBy(k string, refs []string, key string) bool {
+	for _, f := range append([]string{k}, refs...) {
+		if f == key || strings.HasPrefix(f, key+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// preload loads the body files and the schemas of the imposter. The files that changed since they
+// were cached are loaded again, as the watcher only reports one of the files changed at the same time.
+// The body files of templates too big to be rendered are reported too
+func (c *FileCache) preload(i Imposter) []error {
+	var paths []string
+	templates := make(map[string]bool)
+	for _, res := range i.Response {
+		var bodyFiles []string
+		if res.BodyFile != nil {
+			bodyFiles = append(bodyFiles, i.CalculateFilePath(*res.BodyFile))
+		}
+		for _, v := range res.Variants {
+			if v.BodyFile != nil {
+				bodyFiles = append(bodyFiles, i.CalculateFilePath(*v.BodyFile))
+			}
+		}
+		for _, path := range bodyFiles {
+			templates[path] = templates[path] || res.Template
+		}
+		paths = append(paths, bodyFiles...)
+	}
+
+	var errs []error
+	for _, path := range paths {
+		c.refresh(path)
+		if f := c.file(path); f.err != nil {
+			errs = append(errs, f.err)
+		} else if f.content == nil && templates[path] {
+			errs = append(errs, fmt.Errorf("the body file %s is bigger than %d bytes, it's streamed without rendering it as a template", path, maxBufferedBodyFileSize))
+		}
+		for _, ext := range precompressedExtensions {
+			c.refresh(path + ext)
+			c.file(path + ext)
+		}
+	}
+
+	if i.Request.SchemaFile != nil {
+		path := i.CalculateFilePath(*i.Request.SchemaFile)
+		c.refresh(path)
+		for _, ref := range c.schemaRefs(path) {
+			c.refresh(ref)
+		}
+		if _, err := c.schema(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if i.Request.XSDFile != nil {
+		path := i.CalculateFilePath(*i.Request.XSDFile)
+		c.refresh(path)
+		for _, ref := range c.xsdRefs(path) {
+			c.refresh(ref)
+		}
+		if _, err := c.xsd(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// parseBodyTemplates parses the body files of the responses that are templates, so an invalid
+// template fails the load of the imposter instead of each request
+func (c *FileCache) parseBodyTemplates(i Imposter) error {
+	responses := i.Response
+	if i.RateLimit != nil && i.RateLimit.Response != nil {
+		responses = append(responses[:len(responses):len(responses)], *i.RateLimit.Response)
+	}
+
+	for _, res := range responses {
+		if !res.Template {
+			continue
+		}
+
+		var bodyFiles []string
+		if res.BodyFile != nil {
+			bodyFiles = append(bodyFiles, *res.BodyFile)
+		}
+		for _, v := range res.Variants {
+			if v.BodyFile != nil {
+				bodyFiles = append(bodyFiles, *v.BodyFile)
+			}
+		}
+
+		for _, bodyFile := range bodyFiles {
+			if _, err := c.template(i.CalculateFilePath(bodyFile)); err != nil {
+				return fmt.Errorf("%w: the body file %s", err, bodyFile)
+			}
+		}
+	}
+	return nil
+}
+
+// refresh invalidates the file if its size or modification time are different from the cached ones
+func (c *FileCache) refresh(path string) {
+	c.mu.RLock()
+	f, ok := c.files[cacheKey(path)]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if (err == nil) != (f.err == nil) || (err == nil && (info.Size() != f.info.Size() || !info.ModTime().Equal(f.info.ModTime()))) {
+		c.Invalidate(path)
+	}
+}
+
+// file returns the file, loading it from the filesystem the first time it's used.
+// Without cache, the file is loaded each time
+func (c *FileCache) file(path string) cachedFile {
+	if c == nil {
+		return loadFile(path)
+	}
+
+	key := cacheKey(path)
+	c.mu.RLock()
+	f, ok := c.files[key]
+	c.mu.RUnlock()
+	if ok {
+		return f
+	}
+
+	f = loadFile(path)
+	c.mu.Lock()
+	c.files[key] = f
+	c.mu.Unlock()
+	return f
+}
+
+// template returns the body file parsed as a template, parsing it the first time it's used.
+// Without cache, the file is parsed each time
+func (c *FileCache) template(path string) (*template.Template, error) {
+	if c == nil {
+		return parseTemplate(filepath.Base(path), string(c.file(path).content))
+	}
+
+	key := cacheKey(path)
+	c.mu.RLock()
+	t, ok := c.templates[key]
+	c.mu.RUnlock()
+	if ok {
+		return t.tmpl, t.err
+	}
+
+	t.tmpl, t.err = parseTemplate(filepath.Base(path), string(c.file(path).content))
+	c.mu.Lock()
+	c.templates[key] = t
+	c.mu.Unlock()
+	return t.tmpl, t.err
+}
+
+// schema returns the schema compiled, compiling it the first time it's used.
+// Without cache, the schema is compiled each time
+func (c *FileCache) schema(path string) (*gojsonschema.Schema, error) {
+	if c == nil {
+		return compileSchema(path, c.file(path))
+	}
+
+	key := cacheKey(path)
+	c.mu.RLock()
+	s, ok := c.schemas[key]
+	c.mu.RUnlock()
+	if ok {
+		return s.schema, s.err
+	}
+
+	s.schema, s.err = compileSchema(path, c.file(path))
+	s.refs = c.collectRefs(path)
+	c.mu.Lock()
+	c.schemas[key] = s
+	c.mu.Unlock()
+	return s.schema, s.err
+}
+
+// schemaRefs returns the files the cached schema refers to
+func (c *FileCache) schemaRefs(path string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.schemas[cacheKey(path)].refs
+}
+
+// collectRefs returns the files the schema refers to with $ref, directly or through other
+// files, and caches them so their changes are detected
+func (c *FileCache) collectRefs(path string) []string {
+	var refs []string
+	visited := map[string]bool{cacheKey(path): true}
+	pending := []string{path}
+	for len(pending) > 0 {
+		path, pending = pending[0], pending[1:]
+		for _, ref := range fileRefs(path, c.file(path).content) {
+			if key := cacheKey(ref); !visited[key] {
+				visited[key] = true
+				refs = append(refs, key)
+				pending = append(pending, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// fileRefs returns the paths of the files referred by the $ref of a schema, relative to the schema file.
+// The references to the same document or to remote URLs are ignored
+func fileRefs(path string, content []byte) []string {
+	var schema interface{}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil
+	}
+
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				file, _, _ := strings.Cut(ref, "#")
+				if u, err := url.Parse(file); err == nil && file != "" && u.Scheme == "" {
+					refs = append(refs, filepath.Join(filepath.Dir(path), filepath.FromSlash(u.Path)))
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(schema)
+	return refs
+}
+
+// xsd returns the XSD compiled, compiling it the first time it's used.
+// Without cache, the XSD is compiled each time
+func (c *FileCache) xsd(path string) (*xsdSchema, error) {
+	if c == nil {
+		schema, _, err := compileXSD(path)
+		return schema, err
+	}
+
+	key := cacheKey(path)
+	c.mu.RLock()
+	x, ok := c.xsds[key]
+	c.mu.RUnlock()
+	if ok {
+		return x.schema, x.err
+	}
+
+	// the files are cached only to detect their changes, the XSD is compiled from the filesystem
+	if f := c.file(path); f.err != nil {
+		return nil, f.err
+	}
+	x.schema, x.refs, x.err = compileXSD(path)
+	for _, ref := range x.refs {
+		c.file(ref)
+	}
+
+	c.mu.Lock()
+	c.xsds[key] = x
+	c.mu.Unlock()
+	return x.schema, x.err
+}
+
+// xsdRefs returns the files included or imported by the cached XSD
+func (c *FileCache) xsdRefs(path string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.xsds[cacheKey(path)].refs
+}
+
+func loadFile(path string) cachedFile {
+	info, err := os.Stat(path)
+	if err != nil {
+		return cachedFile{err: err}
+	}
+	if info.Size() > maxBufferedBodyFileSize {
+		return cachedFile{info: info}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cachedFile{err: err}
+	}
+	return cachedFile{content: content, info: info}
+}
+
+// compileSchema compiles the schema from its file, so the $ref to other files are resolved relative to it
+func compileSchema(path string, f cachedFile) (*gojsonschema.Schema, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewReferenceLoader((&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()))
+}
+
+// open returns a reader of the file content, the files not kept in memory are opened from the filesystem
+func (f cachedFile) open(path string) (io.ReadSeeker, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.content == nil {
+		return os.Open(path)
+	}
+	return bytes.NewReader(f.content), nil
+}
+
+// cacheKey identifies the files by their absolute path, as the watcher and the imposters may refer
+// to the same file with different relative paths
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestFileCache_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gopher.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "Zebediah"}`), 0o644))
+
+	bodyFile := "gopher.json"
+	imposter := Imposter{
+		BasePath: dir,
+		Request:  Request{Method: http.MethodGet, Endpoint: "/gophers/1"},
+		Response: Responses{{Status: http.StatusOK, BodyFile: &bodyFile}},
+	}
+
+	cache := NewFileCache()
+	assert.Empty(t, cache.preload(imposter))
+	imposter.files = cache
+
+	serve := func() string {
+		rec := httptest.NewRecorder()
+		ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers/1", nil))
+		return rec.Body.String()
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "Gopher"}`), 0o644))
+	assert.Equal(t, `{"name": "Zebediah"}`, serve(), "the body file must be served from the cache")
+
+	cache.Invalidate(dir)
+	assert.Equal(t, `{"name": "Gopher"}`, serve())
+
+	require.NoError(t, os.Remove(path))
+	assert.Equal(t, `{"name": "Gopher"}`, serve())
+
+	assert.Len(t, cache.preload(imposter), 1, "the removed file must be refreshed when the cache is preloaded")
+	rec := httptest.NewRecorder()
+	ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestFileCache_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gopher.json")
+	require.NoError(t, os.WriteFile(path, []byte("Zebediah"), 0o644))
+
+	cache := NewFileCache()
+	assert.Equal(t, []byte("Zebediah"), cache.file(path).content)
+
+	require.NoError(t, os.WriteFile(path, []byte("Gopher"), 0o644))
+	modtime := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, modtime, modtime))
+	assert.Equal(t, []byte("Zebediah"), cache.file(path).content)
+
+	cache.refresh(path)
+	assert.Equal(t, []byte("Gopher"), cache.file(path).content)
+}
+
+func TestFileCache_Schema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gopher.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "object", "required": ["name"]}`), 0o644))
+
+	schemaFile := "gopher.json"
+	imposter := Imposter{
+		BasePath: dir,
+		Request:  Request{Method: http.MethodPost, Endpoint: "/gophers", SchemaFile: &schemaFile},
+		files:    NewFileCache(),
+	}
+
+	validate := func(body string) error {
+		req := httptest.NewRequest(http.MethodPost, "/gophers", bytes.NewBufferString(body))
+		err := validateSchema(imposter, req)
+
+		restored, _ := io.ReadAll(req.Body)
+		assert.Equal(t, body, string(restored))
+		return err
+	}
+
+	assert.NoError(t, validate(`{"name": "Zebediah"}`))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "object", "required": ["color"]}`), 0o644))
+	assert.NoError(t, validate(`{"name": "Zebediah"}`), "the schema must be compiled once")
+
+	imposter.files.Invalidate(path)
+	assert.Error(t, validate(`{"name": "Zebediah"}`))
+}
+
+func TestFileCache_SchemaRefs(t *testing.T) {
+	dir := t.TempDir()
+	path, definitions := filepath.Join(dir, "gopher.json"), filepath.Join(dir, "common", "definitions.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(definitions), 0o755))
+	require.NoError(t, os.WriteFile(definitions, []byte(`{"definitions": {"name": {"type": "string"}}}`), 0o644))
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"type": "object",
+		"properties": {"name": {"$ref": "common/definitions.json#/definitions/name"}, "self": {"$ref": "#/properties/name"}}
+	}`), 0o644))
+
+	cache := NewFileCache()
+	schema, err := cache.schema(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{cacheKey(definitions)}, cache.schemaRefs(path))
+
+	res, err := schema.Validate(gojsonschema.NewStringLoader(`{"name": 1}`))
+	require.NoError(t, err)
+	assert.False(t, res.Valid())
+
+	require.NoError(t, os.WriteFile(definitions, []byte(`{"definitions": {"name": {"type": "integer"}}}`), 0o644))
+	cache.Invalidate(filepath.Join(dir, "common"))
+
+	schema, err = cache.schema(path)
+	require.NoError(t, err)
+	res, err = schema.Validate(gojsonschema.NewStringLoader(`{"name": 1}`))
+	require.NoError(t, err)
+	assert.True(t, res.Valid(), "the schema must be compiled again when a referred file changes")
+}
@@ -0,0 +1,292 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	formURLEncodedContentType = "application/x-www-form-urlencoded"
+	multipartFormContentType  = "multipart/form-data"
+)
+
+var errInvalidPattern = errors.New("invalid pattern")
+
+// Pattern is a regular expression of the form fields, files and cookies of the request,
+// compiled when the imposter is unmarshalled. The empty pattern matches any value
+type Pattern struct {
+	re *regexp.Regexp
+}
+
+// newPattern compiles the regular expression of the pattern
+func newPattern(pattern string) (Pattern, error) {
+	if pattern == "" {
+		return Pattern{}, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("%w: %v", errInvalidPattern, err)
+	}
+	return Pattern{re: re}, nil
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it compiles the pattern.
+func (p *Pattern) UnmarshalJSON(data []byte) error {
+	var pattern string
+	if err := json.Unmarshal(data, &pattern); err != nil {
+		return err
+	}
+
+	var err error
+	*p, err = newPattern(pattern)
+	return err
+}
+
+// MarshalJSON of json.Marshaler interface.
+func (p Pattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it compiles the pattern.
+func (p *Pattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var pattern string
+	if err := unmarshal(&pattern); err != nil {
+		return err
+	}
+
+	var err error
+	*p, err = newPattern(pattern)
+	return err
+}
+
+// MarshalYAML of yaml.Marshaler interface.
+func (p Pattern) MarshalYAML() (interface{}, error) {
+	return p.String(), nil
+}
+
+// String returns the source of the regular expression
+func (p Pattern) String() string {
+	if p.re == nil {
+		return ""
+	}
+	return p.re.String()
+}
+
+// MatchString checks if the value matches the regular expression
+func (p Pattern) MatchString(value string) bool {
+	return p.re == nil || p.re.MatchString(value)
+}
+
+// FileMatcher restricts the files of a multipart/form-data request, the Filename and
+// ContentType are regular expressions and the sizes, in bytes, are inclusive
+type FileMatcher struct {
+	Filename    Pattern `json:"filename,omitempty" yaml:"filename,omitempty"`
+	ContentType Pattern `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	MinSize     *int64  `json:"minSize,omitempty" yaml:"minSize,omitempty"`
+	MaxSize     *int64  `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+}
+
+// requestForm are the fields and files of the body of a form request
+type requestForm struct {
+	values url.Values
+	files  map[string][]formFile
+}
+
+// formFile describes a file of a multipart/form-data request
+type formFile struct {
+	filename    string
+	contentType string
+	size        int64
+}
+
+// MatcherByForm check if the form fields and files of the request matching with the imposter
+func MatcherByForm(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		err := validateForm(imposter, req)
+		if err != nil {
+			loggerFromContext(req.Context()).Debug("request does not match the form",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"error", err)
+			return false
+		}
+		return true
+	}
+}
+
+func validateForm(imposter Imposter, req *http.Request) error {
+	if imposter.Request.Form == nil && imposter.Request.Files == nil {
+		return nil
+	}
+
+	form, err := parseRequestForm(req)
+	if err != nil {
+		return err
+	}
+
+	if imposter.Request.Form != nil {
+		for k, v := range *imposter.Request.Form {
+			if !matchesAnyValue(form.values[k], v) {
+				return fmt.Errorf("the form field %s doesn't match %q", k, v.String())
+			}
+		}
+	}
+
+	if imposter.Request.Files != nil {
+		for k, f := range *imposter.Request.Files {
+			if !f.matches(form.files[k]) {
+				return fmt.Errorf("the file %s doesn't match", k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseRequestForm reads the fields and files of an application/x-www-form-urlencoded or
+// multipart/form-data body, leaving the body ready to be read again
+func parseRequestForm(req *http.Request) (requestForm, error) {
+	form := requestForm{values: url.Values{}, files: map[string][]formFile{}}
+	if req.Body == nil {
+		return form, errors.New("unexpected empty body request")
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return form, fmt.Errorf("%w: impossible read the request body", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return form, fmt.Errorf("%w: invalid content type", err)
+	}
+
+	switch mediaType {
+	case formURLEncodedContentType:
+		form.values, err = url.ParseQuery(string(body))
+		if err != nil {
+			return form, fmt.Errorf("%w: invalid form body", err)
+		}
+		return form, nil
+	case multipartFormContentType:
+		return form, parseMultipartForm(body, params["boundary"], &form)
+	}
+	return form, fmt.Errorf("unexpected content type %s for a form", mediaType)
+}
+
+func parseMultipartForm(body []byte, boundary string, form *requestForm) error {
+	if boundary == "" {
+		return errors.New("missing multipart boundary")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: invalid multipart body", err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			var value strings.Builder
+			if _, err := io.Copy(&value, part); err != nil {
+				return fmt.Errorf("%w: invalid multipart body", err)
+			}
+			form.values.Add(name, value.String())
+			continue
+		}
+
+		size, err := io.Copy(io.Discard, part)
+		if err != nil {
+			return fmt.Errorf("%w: invalid multipart body", err)
+		}
+		form.files[name] = append(form.files[name], formFile{
+			filename:    part.FileName(),
+			contentType: part.Header.Get("Content-Type"),
+			size:        size,
+		})
+	}
+}
+
+// matches checks if any of the files satisfies the matcher
+func (m FileMatcher) matches(files []formFile) bool {
+	for _, f := range files {
+		if m.matchesFile(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m FileMatcher) matchesFile(f formFile) bool {
+	if !m.Filename.MatchString(f.filename) {
+		return false
+	}
+
+	if !m.ContentType.MatchString(f.contentType) {
+		return false
+	}
+
+	if m.MinSize != nil && f.size < *m.MinSize {
+		return false
+	}
+
+	if m.MaxSize != nil && f.size > *m.MaxSize {
+		return false
+	}
+
+	return true
+}
+
+// String describes the matcher, as it's shown by the diagnostics
+func (m FileMatcher) String() string {
+	var desc []string
+	if m.Filename.re != nil {
+		desc = append(desc, "filename "+m.Filename.String())
+	}
+	if m.ContentType.re != nil {
+		desc = append(desc, "content type "+m.ContentType.String())
+	}
+	if m.MinSize != nil {
+		desc = append(desc, fmt.Sprintf("min size %d", *m.MinSize))
+	}
+	if m.MaxSize != nil {
+		desc = append(desc, fmt.Sprintf("max size %d", *m.MaxSize))
+	}
+	if len(desc) == 0 {
+		return "any file"
+	}
+	return strings.Join(desc, ", ")
+}
+
+// String describes the file, as it's shown by the diagnostics
+func (f formFile) String() string {
+	return fmt.Sprintf("%s (%s, %d bytes)", f.filename, f.contentType, f.size)
+}
+
+// matchesAnyValue checks if any of the values matches the pattern,
+// an empty pattern only requires any value to be present
+func matchesAnyValue(values []string, pattern Pattern) bool {
+	for _, v := range values {
+		if pattern.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMatcherByForm(t *testing.T) {
+	maxSize := int64(10)
+	minSize := int64(1)
+
+	urlEncoded := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	testCases := map[string]struct {
+		form  map[string]string
+		files map[string]FileMatcher
+		req   *http.Request
+		want  bool
+	}{
+		"url encoded fields": {
+			form: map[string]string{"name": "^Zebediah$", "color": ""},
+			req:  urlEncoded("name=Zebediah&color=Purple"),
+			want: true,
+		},
+		"url encoded field not matching": {
+			form: map[string]string{"name": "^Zebediah$"},
+			req:  urlEncoded("name=Gopher"),
+		},
+		"url encoded missing field": {
+			form: map[string]string{"color": ""},
+			req:  urlEncoded("name=Zebediah"),
+		},
+		"multipart fields and files": {
+			form:  map[string]string{"name": "Zebediah"},
+			files: map[string]FileMatcher{"avatar": {Filename: pattern(t, `\.png$`), ContentType: pattern(t, "^image/png$"), MinSize: &minSize, MaxSize: &maxSize}},
+			req:   multipartRequest(t, map[string]string{"name": "Zebediah"}, "avatar", "zebediah.png", "image/png", "12345"),
+			want:  true,
+		},
+		"multipart file too big": {
+			files: map[string]FileMatcher{"avatar": {MaxSize: &maxSize}},
+			req:   multipartRequest(t, nil, "avatar", "zebediah.png", "image/png", "12345678901"),
+		},
+		"multipart file with unexpected content type": {
+			files: map[string]FileMatcher{"avatar": {ContentType: pattern(t, "^image/")}},
+			req:   multipartRequest(t, nil, "avatar", "zebediah.txt", "text/plain", "hello"),
+		},
+		"multipart missing file": {
+			files: map[string]FileMatcher{"document": {}},
+			req:   multipartRequest(t, nil, "avatar", "zebediah.png", "image/png", "12345"),
+		},
+		"json body": {
+			form: map[string]string{"name": ""},
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"name":"Zebediah"}`))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			}(),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := Imposter{Request: Request{Method: http.MethodPost, Endpoint: "/gophers"}}
+			if tc.form != nil {
+				imposter.Request.Form = patterns(t, tc.form)
+			}
+			if tc.files != nil {
+				imposter.Request.Files = &tc.files
+			}
+
+			assert.Equal(t, tc.want, MatcherByForm(imposter)(tc.req, nil))
+
+			// the body can be read again by the rest of matchers
+			body, err := io.ReadAll(tc.req.Body)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, body)
+		})
+	}
+}
+
+func TestPattern_Unmarshal(t *testing.T) {
+	var form map[string]Pattern
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "^Zebediah$", "color": ""}`), &form))
+	assert.Equal(t, "^Zebediah$", form["name"].String())
+	assert.True(t, form["color"].MatchString("any"))
+
+	b, err := json.Marshal(form)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "^Zebediah$", "color": ""}`, string(b))
+
+	err = json.Unmarshal([]byte(`{"name": "(Zebediah"}`), &form)
+	assert.True(t, errors.Is(err, errInvalidPattern), "unexpected error %v", err)
+
+	var files map[string]FileMatcher
+	err = yaml.Unmarshal([]byte("avatar:\n  filename: \"[.png\"\n"), &files)
+	assert.True(t, errors.Is(err, errInvalidPattern), "unexpected error %v", err)
+}
+
+func pattern(t *testing.T, pattern string) Pattern {
+	p, err := newPattern(pattern)
+	require.NoError(t, err)
+	return p
+}
+
+func patterns(t *testing.T, m map[string]string) *map[string]Pattern {
+	p := make(map[string]Pattern, len(m))
+	for k, v := range m {
+		p[k] = pattern(t, v)
+	}
+	return &p
+}
+
+func multipartRequest(t *testing.T, fields map[string]string, fileField, filename, contentType, content string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		require.NoError(t, w.WriteField(k, v))
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="`+fileField+`"; filename="`+filename+`"`)
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/gophers", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
@@ -1,57 +1,119 @@
 package http
 
 import (
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ImposterHandler create specific handler for the received imposter
+// ImposterHandler create specific handler for the received imposter, when the proxy
+// of any of its responses is invalid the requests get a 502 Bad Gateway
 func ImposterHandler(i Imposter) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		res := i.NextResponse()
-		if res.Delay.Delay() > 0 {
-			time.Sleep(res.Delay.Delay())
+	proxies, err := newImposterProxies(i)
+	if err != nil {
+		return func(w http.ResponseWriter, r *http.Request) {
+			loggerFromContext(r.Context()).Error("invalid proxy of the imposter", "imposter", i.Path, "error", err)
+			w.WriteHeader(http.StatusBadGateway)
 		}
-		writeHeaders(res, w)
-		w.WriteHeader(res.Status)
-		writeBody(i, res, w)
 	}
+	return imposterHandler(i, proxies)
 }
 
-func writeHeaders(r Response, w http.ResponseWriter) {
-	if r.Headers == nil {
-		return
+func imposterHandler(i Imposter, proxies map[*ResponseProxy]imposterProxy) http.HandlerFunc {
+	var limiter *rateLimiter
+	if i.RateLimit != nil {
+		limiter = newRateLimiter(*i.RateLimit)
 	}
 
-	for key, val := range *r.Headers {
-		w.Header().Set(key, val)
-	}
-}
+	return func(w http.ResponseWriter, r *http.Request) {
+		setMatchedImposter(r, i)
+		if writeSchemaErrors(w, r, i) {
+			return
+		}
+
+		var res Response
+		rateLimited := false
+		if limiter != nil {
+			status := limiter.take(r)
+			limiter.writeHeaders(w, status)
+			if rateLimited = !status.allowed; rateLimited {
+				res = *limiter.cfg.Response
+			}
+		}
+		if !rateLimited {
+			res = i.NextResponse()
+		}
+		res = soapFaultResponse(i, res, r)
+
+		_, span := startSpan(r, "killgrave.response", trace.WithAttributes(
+			attribute.String("killgrave.imposter", i.Path),
+			attribute.Int("killgrave.response.status", res.Status),
+			attribute.Bool("killgrave.rate_limited", rateLimited),
+		))
+		defer span.End()
 
-func writeBody(i Imposter, r Response, w http.ResponseWriter) {
-	wb := []byte(r.Body)
+		if res.Proxy != nil {
+			proxies[res.Proxy].serveHTTP(w, r)
+			return
+		}
+
+		if delay := res.Delay.Delay(); delay > 0 {
+			setDelay(r, delay)
+			span.SetAttributes(attribute.String("killgrave.response.delay", delay.String()))
+			time.Sleep(delay)
+		}
+		res, contentType, ok := negotiateResponse(res, r)
+		if !ok {
+			writeNotAcceptable(w, res)
+			return
+		}
+
+		logger := loggerFromContext(r.Context())
+		data := newResponseTemplateData(r)
+		writeHeaders(i, res, w, data, logger)
+		writeCookies(res, w)
+
+		if res.BodyFile != nil {
+			serveBodyFile(w, r, i, res, contentType, data, logger)
+			return
+		}
 
-	if r.BodyFile != nil {
-		bodyFile := i.CalculateFilePath(*r.BodyFile)
-		wb = fetchBodyFromFile(bodyFile)
+		body := renderBody(i, res, data, logger)
+		writeContentType(w, res, contentType, body)
+		body, err := encodeBody(w, r, i, body)
+		if err != nil {
+			logger.Error("error compressing the body", "imposter", i.Path, "error", err)
+		}
+		w.WriteHeader(res.Status)
+		throttle(w, res.Bandwidth).Write(body)
 	}
-	w.Write(wb)
 }
 
-func fetchBodyFromFile(bodyFile string) (bytes []byte) {
-	if _, err := os.Stat(bodyFile); os.IsNotExist(err) {
-		log.Printf("the body file %s not found\n", bodyFile)
+func writeHeaders(i Imposter, r Response, w http.ResponseWriter, data responseTemplateData, logger *slog.Logger) {
+	if r.Headers == nil {
 		return
 	}
 
-	f, _ := os.Open(bodyFile)
-	defer f.Close()
-	bytes, err := io.ReadAll(f)
+	for key, values := range *r.Headers {
+		w.Header().Del(key)
+		for idx, val := range values {
+			rendered, err := renderTemplate(r.templates.headerTemplate(key, idx), val, data)
+			if err != nil {
+				logger.Error("error rendering the header template", "imposter", i.Path, "header", key, "error", err)
+			}
+			w.Header().Add(key, rendered)
+		}
+	}
+}
+
+// renderBody renders the body of the response, the body files are served by serveBodyFile
+func renderBody(i Imposter, r Response, data responseTemplateData, logger *slog.Logger) []byte {
+	rendered, err := renderTemplate(r.templates.bodyTemplate(), r.Body, data)
 	if err != nil {
-		log.Printf("imposible read the file %s: %v\n", bodyFile, err)
+		logger.Error("error rendering the body template", "imposter", i.Path, "error", err)
 	}
-	return
+	return []byte(rendered)
 }
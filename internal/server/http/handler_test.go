@@ -3,11 +3,14 @@ package http
 import (
 	"bytes"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
 
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,6 +28,8 @@ func TestImposterHandler(t *testing.T) {
 	  }`)
 	var headers = make(map[string]string)
 	headers["Content-Type"] = "application/json"
+	var resHeaders = make(map[string]HeaderValues)
+	resHeaders["Content-Type"] = HeaderValues{"application/json"}
 
 	schemaFile := "test/testdata/imposters/schemas/create_gopher_request.json"
 	bodyFile := "test/testdata/imposters/responses/create_gopher_response.json"
@@ -48,9 +53,9 @@ func TestImposterHandler(t *testing.T) {
 		expectedBody string
 		statusCode   int
 	}{
-		{"valid imposter with body", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &headers, Body: body}}}, body, http.StatusOK},
-		{"valid imposter with bodyFile", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &headers, BodyFile: &bodyFile}}}, string(expectedBodyFileData), http.StatusOK},
-		{"valid imposter with not exists bodyFile", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &headers, BodyFile: &bodyFileFake}}}, "", http.StatusOK},
+		{"valid imposter with body", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &resHeaders, Body: body}}}, body, http.StatusOK},
+		{"valid imposter with bodyFile", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &resHeaders, BodyFile: &bodyFile}}}, string(expectedBodyFileData), http.StatusOK},
+		{"valid imposter with not exists bodyFile", Imposter{Request: validRequest, Response: Responses{{Status: http.StatusOK, Headers: &resHeaders, BodyFile: &bodyFileFake}}}, "the body file test/testdata/imposters/responses/create_gopher_response_fail.json can't be read\n", http.StatusInternalServerError},
 	}
 
 	for _, tt := range dataTest {
@@ -161,3 +166,99 @@ func TestImposterHandler_MultipleRequests(t *testing.T) {
 		assert.Equal(t, "Accepted", rec.Body.String())
 	})
 }
+
+func TestImposterHandler_Proxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Token", r.Header.Get("X-Token"))
+		w.Write([]byte(`{"backend":true}`))
+	}))
+	defer backend.Close()
+
+	overrides := map[string]HeaderValues{"X-Mocked": {"false"}}
+	testCases := map[string]struct {
+		response    Response
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		"passthrough": {
+			response: Response{Proxy: &ResponseProxy{
+				Url:            backend.URL,
+				StripPrefix:    "/legacy",
+				RequestHeaders: killgrave.ConfigHeaderRules{Set: map[string]string{"X-Token": "abc"}},
+			}},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"X-Path": "/gophers/1", "X-Token": "abc"},
+		},
+		"passthrough with overrides": {
+			response: Response{
+				Status:  http.StatusCreated,
+				Headers: &overrides,
+				Proxy: &ResponseProxy{
+					Url:         backend.URL,
+					RewritePath: &killgrave.ConfigRewrite{Pattern: "^/legacy/(.*)$", Replacement: "/v2/$1"},
+				},
+			},
+			wantStatus:  http.StatusCreated,
+			wantHeaders: map[string]string{"X-Path": "/v2/gophers/1", "X-Mocked": "false"},
+		},
+		"invalid proxy": {
+			response:   Response{Proxy: &ResponseProxy{Url: backend.URL, Timeout: "soon"}},
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := Imposter{
+				Request:  Request{Method: http.MethodGet, Endpoint: "/legacy/gophers/{id}"},
+				Response: Responses{tc.response},
+			}
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/legacy/gophers/1", nil))
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+			if tc.wantStatus != http.StatusBadGateway {
+				assert.JSONEq(t, `{"backend":true}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_ImposterProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "Proxied")
+	}))
+	defer backend.Close()
+
+	var logs bytes.Buffer
+	metrics := NewMetrics()
+	router := mux.NewRouter()
+	httpServer := &http.Server{Handler: router}
+	srv := NewServer(router, httpServer, &Proxy{}, false, ImposterFs{},
+		WithName("gophers"), WithMetrics(metrics), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	httpServer.Handler = srv.instrument(router)
+
+	srv.addImposterHandler([]Imposter{
+		{Path: "proxied.imp.json", Request: Request{Method: http.MethodGet, Endpoint: "/gophers"}, Response: Responses{{Proxy: &ResponseProxy{Url: backend.URL}}}},
+		{Path: "invalid.imp.json", Request: Request{Method: http.MethodGet, Endpoint: "/cats"}, Response: Responses{{Proxy: &ResponseProxy{Url: backend.URL, Timeout: "soon"}}}},
+	})
+	assert.Contains(t, logs.String(), "the imposter is skipped", "the invalid proxies are reported when the imposters are loaded")
+
+	rec := httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers", nil))
+	assert.Equal(t, "Proxied", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cats", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	NewAdminHandler(metrics).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `killgrave_proxied_requests_total{mode="none",server="gophers"} 1`)
+	assert.NotContains(t, rec.Body.String(), `killgrave_imposter_requests_total`)
+}
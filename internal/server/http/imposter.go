@@ -1,7 +1,9 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -10,9 +12,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	killgrave "github.com/friendsofgo/killgrave/internal"
 	"gopkg.in/yaml.v2"
 )
 
+var errInvalidRequest = errors.New("invalid request")
+
 // ImposterType allows to know the imposter type we're dealing with
 type ImposterType int
 
@@ -37,11 +42,14 @@ type ImposterConfig struct {
 
 // Imposter define an imposter structure
 type Imposter struct {
-	BasePath string    `json:"-" yaml:"-"`
-	Path     string    `json:"-" yaml:"-"`
-	Request  Request   `json:"request"`
-	Response Responses `json:"response"`
-	resIdx   int
+	BasePath    string       `json:"-" yaml:"-"`
+	Path        string       `json:"-" yaml:"-"`
+	Request     Request      `json:"request"`
+	Response    Responses    `json:"response"`
+	RateLimit   *RateLimit   `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	Compression *Compression `json:"compression,omitempty" yaml:"compression,omitempty"`
+	resIdx      int
+	files       *FileCache
 }
 
 // NextResponse returns the imposter's response.
@@ -59,20 +67,107 @@ func (i *Imposter) CalculateFilePath(filePath string) string {
 
 // Request represent the structure of real request
 type Request struct {
-	Method     string             `json:"method"`
-	Endpoint   string             `json:"endpoint"`
-	SchemaFile *string            `json:"schemaFile"`
-	Params     *map[string]string `json:"params"`
-	Headers    *map[string]string `json:"headers"`
+	Method            string                  `json:"method"`
+	Endpoint          string                  `json:"endpoint"`
+	SchemaFile        *string                 `json:"schemaFile"`
+	SchemaErrorStatus int                     `json:"schemaErrorStatus,omitempty" yaml:"schemaErrorStatus,omitempty"`
+	Params            *map[string]string      `json:"params"`
+	Query             *RequestQuery           `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers           *map[string]string      `json:"headers"`
+	Auth              *RequestAuth            `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Form              *map[string]Pattern     `json:"form,omitempty" yaml:"form,omitempty"`
+	Files             *map[string]FileMatcher `json:"files,omitempty" yaml:"files,omitempty"`
+	Cookies           *map[string]Pattern     `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	Predicates        []Predicate             `json:"predicates,omitempty" yaml:"predicates,omitempty"`
+	XSDFile           *string                 `json:"xsdFile,omitempty" yaml:"xsdFile,omitempty"`
+	SOAP              *RequestSOAP            `json:"soap,omitempty" yaml:"soap,omitempty"`
+	Namespaces        map[string]string       `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the request.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type request Request
+	if err := json.Unmarshal(data, (*request)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the request.
+func (r *Request) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type request Request
+	if err := unmarshal((*request)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+func (r *Request) validate() error {
+	if r.SchemaErrorStatus != 0 && (r.SchemaErrorStatus < 400 || r.SchemaErrorStatus > 499) {
+		return fmt.Errorf("%w: the schemaErrorStatus %d must be a 4xx status", errInvalidRequest, r.SchemaErrorStatus)
+	}
+
+	if len(r.Namespaces) > 0 {
+		for i := range r.Predicates {
+			if err := r.Predicates[i].compileXPath(r.Namespaces); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // Response represent the structure of real response
 type Response struct {
-	Status   int                `json:"status"`
-	Body     string             `json:"body"`
-	BodyFile *string            `json:"bodyFile" yaml:"bodyFile"`
-	Headers  *map[string]string `json:"headers"`
-	Delay    ResponseDelay      `json:"delay" yaml:"delay"`
+	Status    int                      `json:"status"`
+	Body      string                   `json:"body"`
+	BodyFile  *string                  `json:"bodyFile" yaml:"bodyFile"`
+	Headers   *map[string]HeaderValues `json:"headers"`
+	Delay     ResponseDelay            `json:"delay" yaml:"delay"`
+	Proxy     *ResponseProxy           `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Cookies   []ResponseCookie         `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	Variants  []ResponseVariant        `json:"variants,omitempty" yaml:"variants,omitempty"`
+	Bandwidth Bandwidth                `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty"`
+	SOAPFault *SOAPFault               `json:"soapFault,omitempty" yaml:"soapFault,omitempty"`
+	// Interpolate allows the references to environment variables and files on the bodies of the response,
+	// when the imposters of the source are interpolated
+	Interpolate bool `json:"interpolate,omitempty" yaml:"interpolate,omitempty"`
+	// Template renders the body, the body file and the headers of the response as Go templates
+	// with the data of the request
+	Template bool `json:"template,omitempty" yaml:"template,omitempty"`
+
+	templates *responseTemplates
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the SOAP fault and parses the templates of the response.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type response Response
+	if err := json.Unmarshal(data, (*response)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the SOAP fault and parses the templates of the response.
+func (r *Response) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type response Response
+	if err := unmarshal((*response)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+func (r *Response) validate() error {
+	if r.SOAPFault != nil {
+		if err := r.SOAPFault.validateDetail(r.Template); err != nil {
+			return err
+		}
+	}
+
+	if !r.Template {
+		return nil
+	}
+	return r.parseTemplates()
 }
 
 // Responses is a wrapper for Response, to allow the use of either a single
@@ -128,8 +223,22 @@ func (rr *Responses) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 type ImposterFs struct {
-	path string
-	fs   fs.FS
+	path        string
+	fs          fs.FS
+	source      string
+	tmpDir      string
+	interpolate bool
+}
+
+// ImposterFsOpt function that allow modify an ImposterFs
+type ImposterFsOpt func(ifs *ImposterFs)
+
+// WithInterpolation resolves the references to environment variables and files of the imposter files,
+// except on the bodies of the responses that don't allow it. It's ignored by the remote sources
+func WithInterpolation() ImposterFsOpt {
+	return func(ifs *ImposterFs) {
+		ifs.interpolate = true
+	}
 }
 
 func NewImposterFS(path string) (ImposterFs, error) {
@@ -191,9 +300,19 @@ func (ifs ImposterFs) unmarshalImposters(imposterConfig ImposterConfig) ([]Impos
 
 	switch imposterConfig.Type {
 	case JSONImposter:
-		parseError = json.Unmarshal(bytes, &imposters)
+		if ifs.interpolate {
+			bytes, parseError = interpolateImposters(bytes, unmarshalJSONNumbers, json.Marshal)
+		}
+		if parseError == nil {
+			parseError = json.Unmarshal(bytes, &imposters)
+		}
 	case YAMLImposter:
-		parseError = yaml.Unmarshal(bytes, &imposters)
+		if ifs.interpolate {
+			bytes, parseError = interpolateImposters(bytes, yaml.Unmarshal, yaml.Marshal)
+		}
+		if parseError == nil {
+			parseError = yaml.Unmarshal(bytes, &imposters)
+		}
 	default:
 		parseError = fmt.Errorf("unsupported imposter type %v", imposterConfig.Type)
 	}
@@ -209,3 +328,61 @@ func (ifs ImposterFs) unmarshalImposters(imposterConfig ImposterConfig) ([]Impos
 
 	return imposters, nil
 }
+
+// interpolateImposters resolves the references to environment variables and files of the string values
+// of the imposters file. The bodies of the responses are left as they are, unless the response allows it
+func interpolateImposters(data []byte, unmarshal func([]byte, interface{}) error, marshal func(interface{}) ([]byte, error)) ([]byte, error) {
+	var doc interface{}
+	if err := unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	doc, err := interpolateValue(doc, false)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(doc)
+}
+
+// unmarshalJSONNumbers keeps the numbers as they are, instead of converting them to float64
+func unmarshalJSONNumbers(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func interpolateValue(v interface{}, bodies bool) (interface{}, error) {
+	var err error
+	switch v := v.(type) {
+	case string:
+		interpolated, err := killgrave.Interpolate([]byte(v), os.LookupEnv, nil)
+		return string(interpolated), err
+	case []interface{}:
+		for i := range v {
+			if v[i], err = interpolateValue(v[i], bodies); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		bodies = bodies || v["interpolate"] == true
+		for k := range v {
+			if k == "body" && !bodies {
+				continue
+			}
+			if v[k], err = interpolateValue(v[k], bodies); err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		bodies = bodies || v["interpolate"] == true
+		for k := range v {
+			if k == "body" && !bodies {
+				continue
+			}
+			if v[k], err = interpolateValue(v[k], bodies); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+)
+
+// ResponseProxy sends the requests that match the imposter to another server, instead of
+// responding with the imposter. The status, headers and delay of the imposter response
+// override the ones of the proxied response
+type ResponseProxy struct {
+	Url            string                      `json:"url" yaml:"url"`
+	StripPrefix    string                      `json:"stripPrefix" yaml:"stripPrefix"`
+	RewritePath    *killgrave.ConfigRewrite    `json:"rewritePath" yaml:"rewritePath"`
+	RequestHeaders killgrave.ConfigHeaderRules `json:"requestHeaders" yaml:"requestHeaders"`
+	Timeout        string                      `json:"timeout" yaml:"timeout"`
+	TLS            killgrave.ConfigProxyTLS    `json:"tls" yaml:"tls"`
+}
+
+// imposterProxy is the upstream, and the overrides of its responses, of a ResponseProxy
+type imposterProxy struct {
+	upstream  *proxyUpstream
+	transform *proxyTransform
+}
+
+// newImposterProxies creates the upstreams of the responses of the imposter that are proxied,
+// failing when any of them is invalid
+func newImposterProxies(i Imposter) (map[*ResponseProxy]imposterProxy, error) {
+	responses := i.Response
+	if i.RateLimit != nil && i.RateLimit.Response != nil {
+		responses = append(Responses{*i.RateLimit.Response}, responses...)
+	}
+
+	proxies := make(map[*ResponseProxy]imposterProxy)
+	for _, res := range responses {
+		if res.Proxy == nil {
+			continue
+		}
+
+		upstream, err := newProxyUpstream(killgrave.ConfigProxyRule{
+			Url:            res.Proxy.Url,
+			PathPrefix:     res.Proxy.StripPrefix,
+			StripPrefix:    res.Proxy.StripPrefix != "",
+			RewritePath:    res.Proxy.RewritePath,
+			RequestHeaders: res.Proxy.RequestHeaders,
+			Timeout:        res.Proxy.Timeout,
+			TLS:            res.Proxy.TLS,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid proxy to %s", err, res.Proxy.Url)
+		}
+
+		transform := &proxyTransform{status: res.Status, delay: res.Delay}
+		if res.Headers != nil {
+			transform.overrides = responseHeader(res.Headers)
+		}
+
+		proxies[res.Proxy] = imposterProxy{upstream: upstream, transform: transform}
+	}
+	return proxies, nil
+}
+
+// serveHTTP sends the request to the upstream of the imposter
+func (p imposterProxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	setProxied(r)
+	p.upstream.serveHTTP(w, withTransform(r, p.transform))
+}
@@ -0,0 +1,271 @@
+package http
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const remoteSourceTimeout = 30 * time.Second
+
+var (
+	// maxRemoteArchiveSize is the maximum size of the archives downloaded from the remote sources
+	maxRemoteArchiveSize int64 = 100 << 20
+	// maxExtractedArchiveSize is the maximum size of all the files extracted from an archive,
+	// so a compressed archive can't fill the disk
+	maxExtractedArchiveSize int64 = 1 << 30
+
+	errArchiveTooBig = errors.New("archive too big")
+)
+
+type archiveType int
+
+const (
+	noArchive archiveType = iota
+	zipArchive
+	tarArchive
+	tarGzArchive
+)
+
+// NewImposterFSFromSource initialize an ImposterFs from a source of imposters, the source
+// can be a local directory or a tar, tar.gz or zip archive, either local or remote (http/https).
+// The archives are extracted into a temporary directory that is removed when calling Close.
+// The options only apply to the local sources, the remote ones are loaded as they are.
+func NewImposterFSFromSource(source string, opts ...ImposterFsOpt) (ImposterFs, error) {
+	archive := detectArchiveType(source)
+
+	if isRemoteSource(source) {
+		if archive == noArchive {
+			return ImposterFs{}, fmt.Errorf("the remote source '%s' must be a tar, tar.gz or zip archive", source)
+		}
+		return newImposterFSFromRemoteArchive(source, archive)
+	}
+
+	ifs, err := newLocalImposterFS(source, archive)
+	if err != nil {
+		return ImposterFs{}, err
+	}
+
+	for _, opt := range opts {
+		opt(&ifs)
+	}
+	return ifs, nil
+}
+
+func newLocalImposterFS(source string, archive archiveType) (ImposterFs, error) {
+	if archive == noArchive {
+		return NewImposterFS(source)
+	}
+
+	if _, err := os.Stat(source); err != nil {
+		return ImposterFs{}, fmt.Errorf("%w: could not read the archive '%s'", err, source)
+	}
+	return newImposterFSFromArchive(source, source, archive)
+}
+
+// Close removes the temporary files created to load the imposters, if any
+func (ifs ImposterFs) Close() error {
+	if ifs.tmpDir == "" {
+		return nil
+	}
+	return os.RemoveAll(ifs.tmpDir)
+}
+
+// Source returns the location from where the imposters are loaded
+func (ifs ImposterFs) Source() string {
+	if ifs.source != "" {
+		return ifs.source
+	}
+	return ifs.path
+}
+
+func isRemoteSource(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func detectArchiveType(source string) archiveType {
+	name := source
+	if u, err := url.Parse(source); err == nil && isRemoteSource(source) {
+		name = u.Path
+	}
+
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zipArchive
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return tarGzArchive
+	case strings.HasSuffix(name, ".tar"):
+		return tarArchive
+	default:
+		return noArchive
+	}
+}
+
+func newImposterFSFromRemoteArchive(source string, archive archiveType) (ImposterFs, error) {
+	client := http.Client{Timeout: remoteSourceTimeout}
+	res, err := client.Get(source)
+	if err != nil {
+		return ImposterFs{}, fmt.Errorf("%w: could not download the imposters from '%s'", err, source)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return ImposterFs{}, fmt.Errorf("could not download the imposters from '%s': unexpected status %d", source, res.StatusCode)
+	}
+	if res.ContentLength > maxRemoteArchiveSize {
+		return ImposterFs{}, fmt.Errorf("%w: the imposters of '%s' are bigger than %d bytes", errArchiveTooBig, source, maxRemoteArchiveSize)
+	}
+
+	f, err := os.CreateTemp("", "killgrave-*"+path.Ext(source))
+	if err != nil {
+		return ImposterFs{}, fmt.Errorf("%w: could not download the imposters from '%s'", err, source)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(res.Body, maxRemoteArchiveSize+1))
+	if err != nil {
+		return ImposterFs{}, fmt.Errorf("%w: could not download the imposters from '%s'", err, source)
+	}
+	if n > maxRemoteArchiveSize {
+		return ImposterFs{}, fmt.Errorf("%w: the imposters of '%s' are bigger than %d bytes", errArchiveTooBig, source, maxRemoteArchiveSize)
+	}
+
+	return newImposterFSFromArchive(source, f.Name(), archive)
+}
+
+func newImposterFSFromArchive(source, archivePath string, archive archiveType) (ImposterFs, error) {
+	tmpDir, err := os.MkdirTemp("", "killgrave-imposters-*")
+	if err != nil {
+		return ImposterFs{}, fmt.Errorf("%w: could not extract the archive '%s'", err, source)
+	}
+
+	switch archive {
+	case zipArchive:
+		err = extractZip(archivePath, tmpDir)
+	case tarArchive, tarGzArchive:
+		err = extractTar(archivePath, tmpDir, archive == tarGzArchive)
+	default:
+		err = fmt.Errorf("unsupported archive type %v", archive)
+	}
+
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return ImposterFs{}, fmt.Errorf("%w: could not extract the archive '%s'", err, source)
+	}
+
+	ifs, err := NewImposterFS(tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return ImposterFs{}, err
+	}
+
+	ifs.source = source
+	ifs.tmpDir = tmpDir
+	return ifs, nil
+}
+
+func extractZip(archivePath, dst string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	remaining := maxExtractedArchiveSize
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveFile(dst, f.Name, rc, &remaining)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(archivePath, dst string, gzipped bool) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	remaining := maxExtractedArchiveSize
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := writeArchiveFile(dst, header.Name, tr, &remaining); err != nil {
+			return err
+		}
+	}
+}
+
+// writeArchiveFile writes the content of a file of an archive into dst, rejecting the names that would
+// be placed outside of it and the content beyond the remaining bytes that can be extracted
+func writeArchiveFile(dst, name string, r io.Reader, remaining *int64) error {
+	target := filepath.Join(dst, filepath.FromSlash(name))
+	if !strings.HasPrefix(target, filepath.Clean(dst)+string(os.PathSeparator)) {
+		return fmt.Errorf("illegal file path in archive: %s", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, *remaining+1))
+	if err != nil {
+		return err
+	}
+	if *remaining -= n; *remaining < 0 {
+		return fmt.Errorf("%w: the extracted files are bigger than %d bytes", errArchiveTooBig, maxExtractedArchiveSize)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sourceImposter = `[{"request": {"method": "GET", "endpoint": "/archived"}, "response": {"status": 200, "bodyFile": "responses/archived.json"}}]`
+
+func TestNewImposterFSFromSource(t *testing.T) {
+	files := map[string]string{
+		"archived.imp.json":       sourceImposter,
+		"responses/archived.json": `{"archived":true}`,
+	}
+
+	dir := t.TempDir()
+	zipPath := writeZipArchive(t, filepath.Join(dir, "imposters.zip"), files)
+	tarPath := writeTarArchive(t, filepath.Join(dir, "imposters.tar"), files, false)
+	tarGzPath := writeTarArchive(t, filepath.Join(dir, "imposters.tar.gz"), files, true)
+
+	remote := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer remote.Close()
+
+	testCases := map[string]string{
+		"zip archive":           zipPath,
+		"tar archive":           tarPath,
+		"tar.gz archive":        tarGzPath,
+		"remote zip archive":    remote.URL + "/imposters.zip",
+		"remote tar.gz archive": remote.URL + "/imposters.tar.gz",
+	}
+
+	for name, source := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ifs, err := NewImposterFSFromSource(source)
+			require.NoError(t, err)
+			defer ifs.Close()
+
+			assert.Equal(t, source, ifs.Source())
+
+			ch := make(chan []Imposter, 1)
+			require.NoError(t, ifs.FindImposters(ch))
+
+			imposters := <-ch
+			require.Len(t, imposters, 1)
+			assert.Equal(t, "/archived", imposters[0].Request.Endpoint)
+
+			body, err := os.ReadFile(imposters[0].CalculateFilePath(*imposters[0].Response[0].BodyFile))
+			require.NoError(t, err)
+			assert.Equal(t, `{"archived":true}`, string(body))
+
+			require.NoError(t, ifs.Close())
+			_, err = os.Stat(ifs.path)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+
+	t.Run("local directory", func(t *testing.T) {
+		ifs, err := NewImposterFSFromSource("test/testdata/imposters")
+		require.NoError(t, err)
+		assert.Equal(t, "test/testdata/imposters", ifs.Source())
+		assert.NoError(t, ifs.Close())
+		assert.DirExists(t, "test/testdata/imposters")
+	})
+
+	t.Run("archive not found", func(t *testing.T) {
+		_, err := NewImposterFSFromSource(filepath.Join(dir, "unknown.zip"))
+		assert.Error(t, err)
+	})
+
+	t.Run("remote source not found", func(t *testing.T) {
+		_, err := NewImposterFSFromSource(remote.URL + "/unknown.zip")
+		assert.Error(t, err)
+	})
+
+	t.Run("remote source is not an archive", func(t *testing.T) {
+		_, err := NewImposterFSFromSource(remote.URL + "/imposters")
+		assert.Error(t, err)
+	})
+
+	t.Run("remote source is not interpolated", func(t *testing.T) {
+		t.Setenv("KILLGRAVE_TEST_ENDPOINT", "/interpolated")
+		writeZipArchive(t, filepath.Join(dir, "interpolated.zip"), map[string]string{
+			"interpolated.imp.json": `[{"request": {"method": "GET", "endpoint": "${KILLGRAVE_TEST_ENDPOINT}"}, "response": {"status": 200}}]`,
+		})
+
+		for source, want := range map[string]string{
+			filepath.Join(dir, "interpolated.zip"): "/interpolated",
+			remote.URL + "/interpolated.zip":       "${KILLGRAVE_TEST_ENDPOINT}",
+		} {
+			ifs, err := NewImposterFSFromSource(source, WithInterpolation())
+			require.NoError(t, err)
+			defer ifs.Close()
+
+			ch := make(chan []Imposter, 1)
+			require.NoError(t, ifs.FindImposters(ch))
+			assert.Equal(t, want, (<-ch)[0].Request.Endpoint, source)
+		}
+	})
+
+	t.Run("archive with files outside of it", func(t *testing.T) {
+		evil := writeZipArchive(t, filepath.Join(dir, "evil.zip"), map[string]string{"../evil.imp.json": sourceImposter})
+		_, err := NewImposterFSFromSource(evil)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewImposterFSFromSource_TooBig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"archived.imp.json": sourceImposter, "responses/archived.json": `{"archived":true}`}
+	zipPath := writeZipArchive(t, filepath.Join(dir, "imposters.zip"), files)
+	tarGzPath := writeTarArchive(t, filepath.Join(dir, "imposters.tar.gz"), files, true)
+	archive, err := os.ReadFile(zipPath)
+	require.NoError(t, err)
+
+	remote := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer remote.Close()
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, b := range archive {
+			w.Write([]byte{b})
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer chunked.Close()
+
+	t.Run("remote archive", func(t *testing.T) {
+		defer func(size int64) { maxRemoteArchiveSize = size }(maxRemoteArchiveSize)
+		maxRemoteArchiveSize = int64(len(archive)) - 1
+
+		for _, source := range []string{remote.URL + "/imposters.zip", chunked.URL + "/imposters.zip"} {
+			_, err := NewImposterFSFromSource(source)
+			assert.True(t, errors.Is(err, errArchiveTooBig), "unexpected error %v", err)
+		}
+	})
+
+	t.Run("extracted files", func(t *testing.T) {
+		defer func(size int64) { maxExtractedArchiveSize = size }(maxExtractedArchiveSize)
+		maxExtractedArchiveSize = int64(len(sourceImposter))
+
+		for _, source := range []string{zipPath, tarGzPath} {
+			_, err := NewImposterFSFromSource(source)
+			assert.True(t, errors.Is(err, errArchiveTooBig), "unexpected error %v", err)
+		}
+	})
+}
+
+func TestServer_ShutdownRemovesArchives(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := writeZipArchive(t, filepath.Join(dir, "imposters.zip"), map[string]string{
+		"archived.imp.json":       sourceImposter,
+		"responses/archived.json": `{"archived":true}`,
+	})
+
+	ifs, err := NewImposterFSFromSource(zipPath)
+	require.NoError(t, err)
+
+	files := NewFileCache()
+	bodyFile := filepath.Join(ifs.tmpDir, "responses", "archived.json")
+	require.NoError(t, files.file(bodyFile).err)
+
+	srv := NewServer(nil, &http.Server{}, nil, false, ifs, WithFileCache(files))
+	require.NoError(t, srv.Shutdown())
+
+	assert.NoDirExists(t, ifs.tmpDir)
+	files.mu.Lock()
+	defer files.mu.Unlock()
+	assert.Empty(t, files.files, "the files of the removed archive must be dropped from the cache")
+}
+
+func writeZipArchive(t *testing.T, archivePath string, files map[string]string) string {
+	f, err := os.Create(archivePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return archivePath
+}
+
+func writeTarArchive(t *testing.T, archivePath string, files map[string]string, gzipped bool) string {
+	f, err := os.Create(archivePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := io.WriteString(tw, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return archivePath
+}
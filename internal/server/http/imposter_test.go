@@ -60,8 +60,8 @@ func TestImposterFS_FindImposters(t *testing.T) {
 		},
 		Response: Responses{{
 			Status: 200,
-			Headers: &map[string]string{
-				"Content-Type": "application/json",
+			Headers: &map[string]HeaderValues{
+				"Content-Type": {"application/json"},
 			},
 			BodyFile: &bodyFile,
 		}},
@@ -133,9 +133,9 @@ func TestImposterFS_FindImposters(t *testing.T) {
 		},
 		Response: Responses{{
 			Status: 201,
-			Headers: &map[string]string{
-				"Content-Type": "application/json",
-				"X-Source":     "YAML",
+			Headers: &map[string]HeaderValues{
+				"Content-Type": {"application/json"},
+				"X-Source":     {"YAML"},
 			},
 			BodyFile: &bodyFile,
 		}},
@@ -154,6 +154,52 @@ func TestImposterFS_FindImposters(t *testing.T) {
 	require.False(t, open)
 }
 
+func TestImposterFS_FindImposters_Interpolation(t *testing.T) {
+	t.Setenv("KILLGRAVE_TEST_HOST", "http://localhost:3000")
+	t.Setenv("KILLGRAVE_TEST_BODY", `{"name":"Zebediah"}`)
+
+	findImposters := func(t *testing.T, ifs ImposterFs) map[string]Imposter {
+		ch := make(chan []Imposter, 2)
+		require.NoError(t, ifs.FindImposters(ch))
+
+		imposters := make(map[string]Imposter)
+		for found := range ch {
+			for _, imposter := range found {
+				imposters[imposter.Request.Endpoint] = imposter
+			}
+		}
+		return imposters
+	}
+
+	t.Run("interpolated imposters", func(t *testing.T) {
+		ifs, err := NewImposterFSFromSource("test/testdata/interpolated_imposters", WithInterpolation())
+		require.NoError(t, err)
+
+		imposters := findImposters(t, ifs)
+		require.Len(t, imposters, 3)
+
+		json := imposters["/default"]
+		assert.Equal(t, "http://localhost:3000/gophers", (*json.Response[0].Headers)["Location"][0])
+		assert.Equal(t, `{"price": "$${KILLGRAVE_TEST_BODY}", "home": "${HOME}", "required": "${KILLGRAVE_TEST_REQUIRED:?}"}`, json.Response[0].Body,
+			"the bodies are left as they are")
+		assert.Equal(t, `{"name":"Zebediah"}`, imposters["/interpolated"].Response[0].Body)
+
+		yaml := imposters["/yaml"]
+		assert.Equal(t, 201, yaml.Response[0].Status)
+		assert.Equal(t, "http://localhost:3000/gophers", (*yaml.Response[0].Headers)["Location"][0])
+		assert.Equal(t, "${HOME}", yaml.Response[0].Body)
+	})
+
+	t.Run("without interpolation", func(t *testing.T) {
+		ifs, err := NewImposterFS("test/testdata/interpolated_imposters")
+		require.NoError(t, err)
+
+		imposters := findImposters(t, ifs)
+		require.Contains(t, imposters, "${KILLGRAVE_TEST_ENDPOINT:-/default}")
+		assert.Equal(t, "${KILLGRAVE_TEST_BODY}", imposters["/interpolated"].Response[0].Body)
+	})
+}
+
 func TestResponses_MarshalJSON(t *testing.T) {
 	tcs := map[string]struct {
 		rr  *Responses
@@ -0,0 +1,293 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errJSONPointerNotFound = errors.New("json pointer not found")
+
+// jsonPatchOp is an operation of a JSON Patch (RFC 6902)
+type jsonPatchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// applyJSONPatch applies the operations, in order, to the decoded JSON document
+func applyJSONPatch(doc interface{}, ops []jsonPatchOp) (interface{}, error) {
+	var err error
+	for _, op := range ops {
+		doc, err = applyJSONPatchOp(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %s", err, op.Op, op.Path)
+		}
+	}
+	return doc, nil
+}
+
+func applyJSONPatchOp(doc interface{}, op jsonPatchOp) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return jsonAdd(doc, op.Path, deepCopyJSON(op.Value))
+	case "remove":
+		return jsonRemove(doc, op.Path)
+	case "replace":
+		if _, err := jsonGet(doc, op.Path); err != nil {
+			return nil, err
+		}
+		doc, err := jsonRemove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, op.Path, deepCopyJSON(op.Value))
+	case "move":
+		v, err := jsonGet(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = jsonRemove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, op.Path, v)
+	case "copy":
+		v, err := jsonGet(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, op.Path, deepCopyJSON(v))
+	case "test":
+		v, err := jsonGet(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(v, op.Value) {
+			return nil, errors.New("test operation failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7386) to the decoded JSON document
+func applyMergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return deepCopyJSON(patch)
+	}
+
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = applyMergePatch(d[k], v)
+	}
+	return d
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) in its reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, errJSONPointerNotFound
+			}
+			doc = v
+		case []interface{}:
+			idx, err := jsonArrayIndex(t, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[idx]
+		default:
+			return nil, errJSONPointerNotFound
+		}
+	}
+	return doc, nil
+}
+
+func jsonAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	return jsonUpdate(doc, pointer, value, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			if key == "-" {
+				return append(p, value), nil
+			}
+			idx, err := jsonArrayIndex(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[idx+1:], p[idx:])
+			p[idx] = value
+			return p, nil
+		}
+		return nil, errJSONPointerNotFound
+	})
+}
+
+func jsonRemove(doc interface{}, pointer string) (interface{}, error) {
+	return jsonUpdate(doc, pointer, nil, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, errJSONPointerNotFound
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			idx, err := jsonArrayIndex(key, len(p)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:idx], p[idx+1:]...), nil
+		}
+		return nil, errJSONPointerNotFound
+	})
+}
+
+// jsonUpdate replaces the parent of the value referenced by the pointer with the result of fn,
+// the root value is replaced by rootValue
+func jsonUpdate(doc interface{}, pointer string, rootValue interface{}, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return rootValue, nil
+	}
+	return jsonUpdateTokens(doc, tokens, fn)
+}
+
+func jsonUpdateTokens(doc interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[tokens[0]]
+		if !ok {
+			return nil, errJSONPointerNotFound
+		}
+		v, err := jsonUpdateTokens(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[tokens[0]] = v
+		return d, nil
+	case []interface{}:
+		idx, err := jsonArrayIndex(tokens[0], len(d)-1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := jsonUpdateTokens(d[idx], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[idx] = v
+		return d, nil
+	}
+	return nil, errJSONPointerNotFound
+}
+
+// jsonArrayIndex parses the reference token of an array, that must be between 0 and max
+func jsonArrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", errJSONPointerNotFound, token)
+	}
+	return idx, nil
+}
+
+// toJSONValue converts a value decoded from YAML into its JSON equivalent, with
+// string keys on the maps and float64 numbers
+func toJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(normalizeYAMLValue(v))
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalizeYAMLValue(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = normalizeYAMLValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = normalizeYAMLValue(v)
+		}
+		return s
+	}
+	return v
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = deepCopyJSON(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = deepCopyJSON(v)
+		}
+		return s
+	}
+	return v
+}
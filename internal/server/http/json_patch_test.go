@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"id":1,"name":"Gopher","tags":["a","b"],"address":{"city":"Madrid"}}`
+
+	testCases := map[string]struct {
+		ops     []jsonPatchOp
+		want    string
+		wantErr bool
+	}{
+		"add field":           {ops: []jsonPatchOp{{Op: "add", Path: "/age", Value: 10.0}}, want: `{"id":1,"name":"Gopher","tags":["a","b"],"address":{"city":"Madrid"},"age":10}`},
+		"add to array":        {ops: []jsonPatchOp{{Op: "add", Path: "/tags/1", Value: "c"}}, want: `{"id":1,"name":"Gopher","tags":["a","c","b"],"address":{"city":"Madrid"}}`},
+		"append to array":     {ops: []jsonPatchOp{{Op: "add", Path: "/tags/-", Value: "c"}}, want: `{"id":1,"name":"Gopher","tags":["a","b","c"],"address":{"city":"Madrid"}}`},
+		"remove field":        {ops: []jsonPatchOp{{Op: "remove", Path: "/address/city"}}, want: `{"id":1,"name":"Gopher","tags":["a","b"],"address":{}}`},
+		"remove from array":   {ops: []jsonPatchOp{{Op: "remove", Path: "/tags/0"}}, want: `{"id":1,"name":"Gopher","tags":["b"],"address":{"city":"Madrid"}}`},
+		"replace field":       {ops: []jsonPatchOp{{Op: "replace", Path: "/name", Value: "Killgrave"}}, want: `{"id":1,"name":"Killgrave","tags":["a","b"],"address":{"city":"Madrid"}}`},
+		"move field":          {ops: []jsonPatchOp{{Op: "move", From: "/address/city", Path: "/city"}}, want: `{"id":1,"name":"Gopher","tags":["a","b"],"address":{},"city":"Madrid"}`},
+		"copy field":          {ops: []jsonPatchOp{{Op: "copy", From: "/tags", Path: "/labels"}}, want: `{"id":1,"name":"Gopher","tags":["a","b"],"labels":["a","b"],"address":{"city":"Madrid"}}`},
+		"successful test":     {ops: []jsonPatchOp{{Op: "test", Path: "/id", Value: 1.0}}, want: doc},
+		"failed test":         {ops: []jsonPatchOp{{Op: "test", Path: "/id", Value: 2.0}}, wantErr: true},
+		"replace missing":     {ops: []jsonPatchOp{{Op: "replace", Path: "/missing", Value: 1.0}}, wantErr: true},
+		"invalid array index": {ops: []jsonPatchOp{{Op: "remove", Path: "/tags/5"}}, wantErr: true},
+		"escaped pointer":     {ops: []jsonPatchOp{{Op: "add", Path: "/a~1b", Value: true}}, want: `{"id":1,"name":"Gopher","tags":["a","b"],"address":{"city":"Madrid"},"a/b":true}`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var d interface{}
+			assert.NoError(t, json.Unmarshal([]byte(doc), &d))
+
+			got, err := applyJSONPatch(d, tc.ops)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			b, err := json.Marshal(got)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(b))
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	var doc, patch interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":"Gopher","address":{"city":"Madrid","zip":"28001"},"tags":["a"]}`), &doc))
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":null,"address":{"zip":"08001"},"tags":["b"],"age":10}`), &patch))
+
+	b, err := json.Marshal(applyMergePatch(doc, patch))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"address":{"city":"Madrid","zip":"08001"},"tags":["b"],"age":10}`, string(b))
+}
@@ -0,0 +1,415 @@
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksCacheTTL is the time the keys fetched from a JWKS url are reused
+	jwksCacheTTL = 5 * time.Minute
+	// jwksRetryInterval is the time to wait before fetching again the keys of a JWKS url that failed
+	jwksRetryInterval = 30 * time.Second
+)
+
+var (
+	errInvalidJWT     = errors.New("invalid jwt")
+	errJWTSignature   = errors.New("invalid jwt signature")
+	errUnsupportedJWK = errors.New("unsupported jwk")
+)
+
+// jwtKey is a key to verify the signature of the tokens, a []byte for the HMAC
+// algorithms, or a *rsa.PublicKey or *ecdsa.PublicKey
+type jwtKey struct {
+	kid string
+	key interface{}
+}
+
+// jwtHeader is the header of a JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jsonWebKey is a public key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jsonWebKeySet is a JWKS (RFC 7517)
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// parseJWT verifies the signature of the token with any of the keys, and returns its claims
+func parseJWT(token string, keys []jwtKey) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: the token must have three parts", errInvalidJWT)
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidJWT, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidJWT, err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range keys {
+		if header.Kid != "" && k.kid != "" && header.Kid != k.kid {
+			continue
+		}
+		if verifyJWTSignature(header.Alg, k.key, signed, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errJWTSignature
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidJWT, err)
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func verifyJWTSignature(alg string, key interface{}, signed, signature []byte) error {
+	hash, err := jwtHash(alg)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		secret, ok := key.([]byte)
+		if !ok {
+			return errJWTSignature
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errJWTSignature
+		}
+		return nil
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errJWTSignature
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest(hash, signed), signature)
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errJWTSignature
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errJWTSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest(hash, signed), r, s) {
+			return errJWTSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported algorithm %q", errInvalidJWT, alg)
+}
+
+func jwtHash(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "HS256", "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "HS384", "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "HS512", "RS512", "ES512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("%w: unsupported algorithm %q", errInvalidJWT, alg)
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// signJWT signs the claims with RS256
+func signJWT(claims map[string]interface{}, key *rsa.PrivateKey, kid string) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "RS256", Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest(crypto.SHA256, []byte(signed)))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// readPublicKeyFile reads a PEM encoded public key or certificate
+func readPublicKeyFile(path string) (jwtKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return jwtKey{}, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return jwtKey{}, fmt.Errorf("no PEM data found on %s", path)
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		return jwtKey{key: cert.PublicKey}, nil
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		return jwtKey{key: pub}, nil
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return jwtKey{}, err
+	}
+	return jwtKey{key: pub}, nil
+}
+
+// parseJWKS returns the keys of the JWKS that can verify tokens
+func parseJWKS(b []byte) ([]jwtKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	var keys []jwtKey
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwtKey{kid: jwk.Kid, key: key})
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %q", errUnsupportedJWK, jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported key type %q", errUnsupportedJWK, jwk.Kty)
+}
+
+// newRSAJWK returns the JWK of a RSA public key, identified by the kid
+func newRSAJWK(pub *rsa.PublicKey, kid string) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// rsaKeyID returns an identifier of the key derived from its modulus
+func rsaKeyID(pub *rsa.PublicKey) string {
+	sum := sha256.Sum256(pub.N.Bytes())
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// jwksCache keeps the keys fetched from the JWKS urls
+var jwksCache = struct {
+	sync.Mutex
+	entries map[string]jwksCacheEntry
+}{entries: make(map[string]jwksCacheEntry)}
+
+type jwksCacheEntry struct {
+	keys     []jwtKey
+	err      error
+	fetched  time.Time
+	failed   time.Time
+	fetching chan struct{}
+}
+
+// retrying reports if the last fetch failed less than jwksRetryInterval ago, so the url isn't fetched yet
+func (e jwksCacheEntry) retrying() bool {
+	return e.err != nil && time.Since(e.failed) < jwksRetryInterval
+}
+
+// jwksKeys returns the keys of the JWKS url. The keys older than jwksCacheTTL are fetched again
+// in the background while the cached ones are returned, so only the first fetch is waited for.
+// After a failed fetch, the url isn't fetched again until jwksRetryInterval has passed, returning
+// the previous keys, if any, or the error of the fetch
+func jwksKeys(url string) ([]jwtKey, error) {
+	jwksCache.Lock()
+	entry := jwksCache.entries[url]
+	if entry.keys != nil {
+		if time.Since(entry.fetched) >= jwksCacheTTL && entry.fetching == nil && !entry.retrying() {
+			entry.fetching = make(chan struct{})
+			jwksCache.entries[url] = entry
+			go refreshJWKS(url, entry.fetching)
+		}
+		jwksCache.Unlock()
+		return entry.keys, nil
+	}
+
+	if entry.fetching == nil {
+		if entry.retrying() {
+			jwksCache.Unlock()
+			return nil, entry.err
+		}
+		entry.fetching = make(chan struct{})
+		jwksCache.entries[url] = entry
+		go refreshJWKS(url, entry.fetching)
+	}
+	fetching := entry.fetching
+	jwksCache.Unlock()
+
+	<-fetching
+
+	jwksCache.Lock()
+	entry = jwksCache.entries[url]
+	jwksCache.Unlock()
+	if entry.keys == nil {
+		return nil, entry.err
+	}
+	return entry.keys, nil
+}
+
+// refreshJWKS fetches the keys of the JWKS url, the previous keys are kept when it fails. An empty set
+// of keys is cached as any other, so the requests aren't verified until it's fetched again
+func refreshJWKS(url string, done chan struct{}) {
+	keys, err := fetchJWKS(url)
+
+	jwksCache.Lock()
+	entry := jwksCache.entries[url]
+	if err == nil {
+		if keys == nil {
+			keys = []jwtKey{}
+		}
+		entry.keys = keys
+		entry.fetched = time.Now()
+	} else {
+		entry.failed = time.Now()
+	}
+	entry.err = err
+	entry.fetching = nil
+	jwksCache.entries[url] = entry
+	jwksCache.Unlock()
+	close(done)
+}
+
+// fetchJWKS fetches the keys of the JWKS url
+func fetchJWKS(url string) ([]jwtKey, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching the jwks %s", res.StatusCode, url)
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid jwks %s", err, url)
+	}
+	return keys, nil
+}
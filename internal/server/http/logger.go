@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type loggerCtxKey struct{}
+
+type requestEntryCtxKey struct{}
+
+// requestEntry collects the information of a request that is written on the access log,
+// the metrics and the trace
+type requestEntry struct {
+	imposter  *Imposter
+	proxied   bool
+	delay     time.Duration
+	matchSpan trace.Span
+}
+
+// WithLogger sets the logger used by the server, by default slog.Default()
+func WithLogger(logger *slog.Logger) ServerOpt {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithAccessLog enables writing an access log entry for each request
+func WithAccessLog() ServerOpt {
+	return func(s *Server) {
+		s.accessLog = true
+	}
+}
+
+// loggerFromContext returns the logger of the server that is handling the request
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// setMatchedImposter annotates the request entry, if any, with the imposter that handles it
+func setMatchedImposter(r *http.Request, imposter Imposter) {
+	if entry, ok := r.Context().Value(requestEntryCtxKey{}).(*requestEntry); ok {
+		entry.imposter = &imposter
+		entry.endMatch()
+	}
+}
+
+// setProxied annotates the request entry, if any, as proxied
+func setProxied(r *http.Request) {
+	if entry, ok := r.Context().Value(requestEntryCtxKey{}).(*requestEntry); ok {
+		entry.proxied = true
+		entry.endMatch()
+	}
+}
+
+// instrument makes the logger of the server, and a place for the errors found by the schema matchers, available for
+// the handlers and matchers, and writes the access log entry, the metrics and the trace of each request when they're enabled
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loggerCtxKey{}, s.logger)
+		ctx = withMatchedSchemaErrors(ctx)
+		if !s.accessLog && s.metrics == nil && s.tracer == nil {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		entry := &requestEntry{}
+		ctx = context.WithValue(ctx, requestEntryCtxKey{}, entry)
+
+		var span trace.Span
+		if s.tracer != nil {
+			ctx, span = s.startRequestSpan(r.WithContext(ctx), entry)
+		}
+
+		start := time.Now()
+		rw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		if span != nil {
+			endRequestSpan(span, entry, rw.status)
+		}
+
+		if s.metrics != nil {
+			s.metrics.observeRequest(s.name, s.proxy.mode.String(), entry, rw.status)
+		}
+
+		if !s.accessLog {
+			return
+		}
+
+		var imposter string
+		if entry.imposter != nil {
+			imposter = entry.imposter.Path
+		}
+
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("imposter", imposter),
+			slog.Bool("proxied", entry.proxied),
+			slog.Int("status", rw.status),
+			slog.Int("bytes", rw.bytes),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// statusResponseWriter records the status code and the size of the response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher, needed by the proxied responses
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the original http.ResponseWriter
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_AccessLog(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "Proxied")
+	}))
+	defer backend.Close()
+
+	proxy, err := NewProxy(backend.URL, killgrave.ProxyMissing)
+	require.NoError(t, err)
+
+	imposterFs, err := NewImposterFS("test/testdata/imposters")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	router := mux.NewRouter()
+	httpServer := &http.Server{Handler: router}
+	srv := NewServer(router, httpServer, proxy, false, imposterFs, WithLogger(logger), WithAccessLog())
+	require.NoError(t, srv.Build())
+
+	testCases := map[string]struct {
+		url      string
+		status   float64
+		imposter string
+		proxied  bool
+	}{
+		"matched imposter": {url: "/testRequest", status: http.StatusOK, imposter: "test_request.imp.json"},
+		"proxied request":  {url: "/NonExistentURL123", status: http.StatusTeapot, proxied: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+
+			w := httptest.NewRecorder()
+			httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", tc.url, nil))
+
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+			assert.Equal(t, "request", entry["msg"])
+			assert.Equal(t, "GET", entry["method"])
+			assert.Equal(t, tc.url, entry["path"])
+			assert.Equal(t, tc.status, entry["status"])
+			assert.Equal(t, tc.imposter, entry["imposter"])
+			assert.Equal(t, tc.proxied, entry["proxied"])
+			assert.Contains(t, entry, "latency")
+		})
+	}
+}
+
+func TestServer_WithoutAccessLog(t *testing.T) {
+	imposterFs, err := NewImposterFS("test/testdata/imposters")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	httpServer := &http.Server{Handler: router}
+	srv := NewServer(router, httpServer, &Proxy{}, false, imposterFs, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	require.NoError(t, srv.Build())
+
+	buf.Reset()
+	w := httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/testRequest", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "killgrave"
+
+// Metrics collects the metrics of the traffic of the mock servers, in Prometheus format.
+// The same Metrics can be shared by several servers, they are distinguished by the server label
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	unmatched *prometheus.CounterVec
+	proxied   *prometheus.CounterVec
+	responses *prometheus.CounterVec
+	delays    *prometheus.HistogramVec
+	reloads   *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics with its own registry
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "imposter_requests_total",
+			Help:      "Number of requests handled by each imposter.",
+		}, []string{"server", "imposter", "method", "endpoint"}),
+		unmatched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "unmatched_requests_total",
+			Help:      "Number of requests that didn't match any imposter and weren't proxied.",
+		}, []string{"server"}),
+		proxied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "proxied_requests_total",
+			Help:      "Number of requests sent to the proxied server, by proxy mode.",
+		}, []string{"server", "mode"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "responses_total",
+			Help:      "Number of responses, by status code.",
+		}, []string{"server", "status"}),
+		delays: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "response_delay_seconds",
+			Help:      "Delay injected by the imposters before responding.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"server", "imposter"}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reloads_total",
+			Help:      "Number of times the imposters have been reloaded by the watcher.",
+		}, []string{"server"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.unmatched,
+		m.proxied,
+		m.responses,
+		m.delays,
+		m.reloads,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// WithMetrics enables the collection of the metrics of the server
+func WithMetrics(m *Metrics) ServerOpt {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// Handler returns the handler that exposes the metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveReload counts a reload of the imposters of the server
+func (m *Metrics) ObserveReload(server string) {
+	m.reloads.WithLabelValues(server).Inc()
+}
+
+// observeRequest updates the metrics with a request already responded
+func (m *Metrics) observeRequest(server, proxyMode string, entry *requestEntry, status int) {
+	m.responses.WithLabelValues(server, strconv.Itoa(status)).Inc()
+
+	switch {
+	case entry.proxied:
+		m.proxied.WithLabelValues(server, proxyMode).Inc()
+	case entry.imposter != nil:
+		m.requests.WithLabelValues(server, entry.imposter.Path, entry.imposter.Request.Method, entry.imposter.Request.Endpoint).Inc()
+		m.delays.WithLabelValues(server, entry.imposter.Path).Observe(entry.delay.Seconds())
+	default:
+		m.unmatched.WithLabelValues(server).Inc()
+	}
+}
+
+// NewAdminHandler returns the handler of the admin listener
+func NewAdminHandler(m *Metrics) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return mux
+}
+
+// setDelay annotates the request entry, if any, with the delay injected before responding
+func setDelay(r *http.Request, delay time.Duration) {
+	if entry, ok := r.Context().Value(requestEntryCtxKey{}).(*requestEntry); ok {
+		entry.delay = delay
+	}
+}
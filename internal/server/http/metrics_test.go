@@ -0,0 +1,51 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Metrics(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "Proxied")
+	}))
+	defer backend.Close()
+
+	proxy, err := NewProxy(backend.URL, killgrave.ProxyMissing)
+	require.NoError(t, err)
+
+	imposterFs, err := NewImposterFS("test/testdata/imposters")
+	require.NoError(t, err)
+
+	metrics := NewMetrics()
+	router := mux.NewRouter()
+	httpServer := &http.Server{Handler: router}
+	srv := NewServer(router, httpServer, proxy, false, imposterFs, WithName("gophers"), WithMetrics(metrics))
+	require.NoError(t, srv.Build())
+
+	for _, url := range []string{"/testRequest", "/testRequest", "/NonExistentURL123"} {
+		httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/testRequest", nil))
+	metrics.ObserveReload("gophers")
+
+	w := httptest.NewRecorder()
+	NewAdminHandler(metrics).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `killgrave_imposter_requests_total{endpoint="/testRequest",imposter="test_request.imp.json",method="GET",server="gophers"} 2`)
+	assert.Contains(t, body, `killgrave_proxied_requests_total{mode="missing",server="gophers"} 1`)
+	assert.Contains(t, body, `killgrave_unmatched_requests_total{server="gophers"} 1`)
+	assert.Contains(t, body, `killgrave_responses_total{server="gophers",status="200"} 3`)
+	assert.Contains(t, body, `killgrave_responses_total{server="gophers",status="405"} 1`)
+	assert.Contains(t, body, `killgrave_response_delay_seconds_count{imposter="test_request.imp.json",server="gophers"} 2`)
+	assert.Contains(t, body, `killgrave_reloads_total{server="gophers"} 1`)
+}
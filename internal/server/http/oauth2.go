@@ -0,0 +1,271 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+)
+
+const (
+	oauth2TokenPath     = "/oauth2/token"
+	oauth2JWKSPath      = "/.well-known/jwks.json"
+	oauth2DiscoveryPath = "/.well-known/openid-configuration"
+
+	defaultOAuth2TokenTTL = time.Hour
+)
+
+// OAuth2Provider is a built-in OAuth2/OIDC provider that issues tokens signed with RS256,
+// that can be verified through its JWKS endpoint
+type OAuth2Provider struct {
+	issuer   string
+	audience string
+	ttl      time.Duration
+	key      *rsa.PrivateKey
+	kid      string
+	clients  []killgrave.ConfigOAuth2Client
+	claims   map[string]string
+	now      func() time.Time
+}
+
+// oauth2Error is the body of the errors of the token endpoint (RFC 6749)
+type oauth2Error struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// oauth2Token is the body of the successful responses of the token endpoint (RFC 6749)
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// NewOAuth2Provider creates the provider, signing the tokens with the key of the signing key file
+// or, if there isn't any, with a new key
+func NewOAuth2Provider(cfg killgrave.ConfigOAuth2) (*OAuth2Provider, error) {
+	p := &OAuth2Provider{
+		issuer:   strings.TrimSuffix(cfg.Issuer, "/"),
+		audience: cfg.Audience,
+		ttl:      defaultOAuth2TokenTTL,
+		clients:  cfg.Clients,
+		claims:   cfg.Claims,
+		now:      time.Now,
+	}
+
+	if cfg.TokenTTL != "" {
+		ttl, err := time.ParseDuration(cfg.TokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid token ttl", err)
+		}
+		p.ttl = ttl
+	}
+
+	var err error
+	if cfg.SigningKeyFile != "" {
+		p.key, err = readPrivateKeyFile(cfg.SigningKeyFile)
+	} else {
+		p.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: impossible to load the signing key of the oauth2 provider", err)
+	}
+
+	p.kid = rsaKeyID(&p.key.PublicKey)
+	return p, nil
+}
+
+// WithOAuth2 serves the token, JWKS and discovery endpoints of the provider
+func WithOAuth2(p *OAuth2Provider) ServerOpt {
+	return func(s *Server) {
+		s.oauth2 = p
+	}
+}
+
+func readPrivateKeyFile(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found on %s", path)
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the signing key must be a RSA key")
+	}
+	return rsaKey, nil
+}
+
+// register adds the endpoints of the provider to the router
+func (p *OAuth2Provider) register(r *mux.Router) {
+	r.HandleFunc(oauth2TokenPath, p.tokenHandler).Methods(http.MethodPost)
+	r.HandleFunc(oauth2JWKSPath, p.jwksHandler).Methods(http.MethodGet)
+	r.HandleFunc(oauth2DiscoveryPath, p.discoveryHandler).Methods(http.MethodGet)
+}
+
+// tokenHandler issues tokens for the client_credentials and password grants
+func (p *OAuth2Provider) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, ok := p.client(clientID, clientSecret)
+	if !ok {
+		writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client", "unknown client or invalid secret")
+		return
+	}
+
+	var subject string
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "client_credentials":
+		subject = clientID
+	case "password":
+		subject = r.PostForm.Get("username")
+		if subject == "" {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "the username is mandatory")
+			return
+		}
+	default:
+		writeOAuth2Error(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("unsupported grant type %q", grantType))
+		return
+	}
+
+	scope := r.PostForm.Get("scope")
+	if scope == "" {
+		scope = strings.Join(client.Scopes, " ")
+	} else if !client.allowsScopes(strings.Fields(scope)) {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_scope", fmt.Sprintf("the client can't request the scope %q", scope))
+		return
+	}
+
+	token, err := p.issue(subject, clientID, scope)
+	if err != nil {
+		writeOAuth2Error(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(oauth2Token{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(p.ttl.Seconds()),
+		Scope:       scope,
+	})
+}
+
+// client returns the configured client with the credentials, any client is accepted if there isn't any configured
+func (p *OAuth2Provider) client(clientID, clientSecret string) (oauth2Client, bool) {
+	if len(p.clients) == 0 {
+		return oauth2Client{}, true
+	}
+
+	for _, c := range p.clients {
+		if c.ClientID == clientID && (c.ClientSecret == "" || secureEqual(c.ClientSecret, clientSecret)) {
+			return oauth2Client(c), true
+		}
+	}
+	return oauth2Client{}, false
+}
+
+type oauth2Client killgrave.ConfigOAuth2Client
+
+// allowsScopes checks that the client can request the scopes, any scope is allowed if the client hasn't scopes
+func (c oauth2Client) allowsScopes(scopes []string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		allowed := false
+		for _, s := range c.Scopes {
+			if s == scope {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// issue signs a new token for the subject
+func (p *OAuth2Provider) issue(subject, clientID, scope string) (string, error) {
+	now := p.now()
+	claims := map[string]interface{}{
+		"iss": p.issuer,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(p.ttl).Unix(),
+	}
+
+	if p.audience != "" {
+		claims["aud"] = p.audience
+	}
+	if clientID != "" {
+		claims["client_id"] = clientID
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+
+	return signJWT(claims, p.key, p.kid)
+}
+
+func (p *OAuth2Provider) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{newRSAJWK(&p.key.PublicKey, p.kid)}})
+}
+
+func (p *OAuth2Provider) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.issuer,
+		"token_endpoint":                        p.issuer + oauth2TokenPath,
+		"jwks_uri":                              p.issuer + oauth2JWKSPath,
+		"grant_types_supported":                 []string{"client_credentials", "password"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func writeOAuth2Error(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(oauth2Error{Error: code, Description: description})
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2Provider(t *testing.T) {
+	router := mux.NewRouter()
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	provider, err := NewOAuth2Provider(killgrave.ConfigOAuth2{
+		Issuer:   srv.URL,
+		Audience: "gophers",
+		Clients: []killgrave.ConfigOAuth2Client{
+			{ClientID: "gopher-app", ClientSecret: "secret", Scopes: []string{"gophers:read", "gophers:write"}},
+		},
+		Claims: map[string]string{"tenant": "killgrave"},
+	})
+	require.NoError(t, err)
+	provider.register(router)
+
+	requestToken := func(form url.Values, clientID, clientSecret string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+oauth2TokenPath, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if clientID != "" {
+			req.SetBasicAuth(clientID, clientSecret)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("issues tokens verifiable with the jwks endpoint", func(t *testing.T) {
+		res := requestToken(url.Values{"grant_type": {"client_credentials"}, "scope": {"gophers:read"}}, "gopher-app", "secret")
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var token oauth2Token
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&token))
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, "gophers:read", token.Scope)
+		assert.Equal(t, 3600, token.ExpiresIn)
+
+		jwt := &JWTAuth{
+			JWKSURL:  srv.URL + oauth2JWKSPath,
+			Issuer:   srv.URL,
+			Audience: "gophers",
+			Claims:   map[string]string{"sub": "^gopher-app$", "tenant": "killgrave"},
+		}
+		require.NoError(t, jwt.validate())
+		imposter := Imposter{Request: Request{Auth: &RequestAuth{JWT: jwt}}}
+		require.NoError(t, imposter.Request.Auth.load(imposter))
+
+		req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		assert.True(t, MatcherByAuth(imposter)(req, nil))
+	})
+
+	t.Run("password grant with credentials on the form", func(t *testing.T) {
+		res := requestToken(url.Values{
+			"grant_type":    {"password"},
+			"username":      {"zebediah"},
+			"password":      {"any"},
+			"client_id":     {"gopher-app"},
+			"client_secret": {"secret"},
+		}, "", "")
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	errorCases := map[string]struct {
+		form         url.Values
+		clientSecret string
+		wantStatus   int
+		wantError    string
+	}{
+		"invalid client":         {form: url.Values{"grant_type": {"client_credentials"}}, clientSecret: "wrong", wantStatus: http.StatusUnauthorized, wantError: "invalid_client"},
+		"unsupported grant type": {form: url.Values{"grant_type": {"authorization_code"}}, clientSecret: "secret", wantStatus: http.StatusBadRequest, wantError: "unsupported_grant_type"},
+		"invalid scope":          {form: url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}, clientSecret: "secret", wantStatus: http.StatusBadRequest, wantError: "invalid_scope"},
+	}
+
+	for name, tc := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			res := requestToken(tc.form, "gopher-app", tc.clientSecret)
+			defer res.Body.Close()
+
+			var body oauth2Error
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+
+	t.Run("discovery document", func(t *testing.T) {
+		res, err := http.Get(srv.URL + oauth2DiscoveryPath)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var discovery map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&discovery))
+		assert.Equal(t, srv.URL, discovery["issuer"])
+		assert.Equal(t, srv.URL+oauth2JWKSPath, discovery["jwks_uri"])
+	})
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pathParamTypes are the patterns of the typed placeholders of the endpoints, like {id:int}
+var pathParamTypes = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"date": `[0-9]{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12][0-9]|3[01])`,
+}
+
+// typedPlaceholder matches the typed placeholders of an endpoint, {name:int}, {name:uuid},
+// {name:date} and {name:enum(a|b|c)}
+var typedPlaceholder = regexp.MustCompile(`\{(\w+):(int|uuid|date|enum\(([^)]*)\))\}`)
+
+// expandEndpoint replaces the typed placeholders of the endpoint with the
+// equivalent gorilla/mux variables with a regular expression
+func expandEndpoint(endpoint string) string {
+	return typedPlaceholder.ReplaceAllStringFunc(endpoint, func(placeholder string) string {
+		m := typedPlaceholder.FindStringSubmatch(placeholder)
+		name, kind := m[1], m[2]
+
+		pattern, ok := pathParamTypes[kind]
+		if !ok {
+			values := strings.Split(m[3], "|")
+			for i, v := range values {
+				values[i] = regexp.QuoteMeta(v)
+			}
+			pattern = "(?:" + strings.Join(values, "|") + ")"
+		}
+
+		return "{" + name + ":" + pattern + "}"
+	})
+}
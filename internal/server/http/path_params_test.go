@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEndpoint(t *testing.T) {
+	testCases := map[string]struct {
+		endpoint string
+		path     string
+		want     bool
+		wantVars map[string]string
+	}{
+		"int":               {endpoint: "/gophers/{id:int}", path: "/gophers/42", want: true, wantVars: map[string]string{"id": "42"}},
+		"not an int":        {endpoint: "/gophers/{id:int}", path: "/gophers/abc"},
+		"uuid":              {endpoint: "/gophers/{id:uuid}", path: "/gophers/8a1c4b5e-3f2d-4c6b-9e7a-1b2c3d4e5f60", want: true},
+		"not an uuid":       {endpoint: "/gophers/{id:uuid}", path: "/gophers/8a1c4b5e"},
+		"date":              {endpoint: "/reports/{day:date}", path: "/reports/2024-02-29", want: true, wantVars: map[string]string{"day": "2024-02-29"}},
+		"not a date":        {endpoint: "/reports/{day:date}", path: "/reports/2024-13-01"},
+		"enum":              {endpoint: "/gophers/{color:enum(purple|brown|blue.ish)}", path: "/gophers/brown", want: true},
+		"not in the enum":   {endpoint: "/gophers/{color:enum(purple|brown|blue.ish)}", path: "/gophers/blueXish"},
+		"several typed":     {endpoint: "/gophers/{id:int}/friends/{friend:uuid}", path: "/gophers/1/friends/8a1c4b5e-3f2d-4c6b-9e7a-1b2c3d4e5f60", want: true},
+		"regular variables": {endpoint: "/gophers/{id:[a-z]+}/{name}", path: "/gophers/abc/zebediah", want: true, wantVars: map[string]string{"id": "abc", "name": "zebediah"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			route := mux.NewRouter().NewRoute().Path(expandEndpoint(tc.endpoint))
+			assert.NoError(t, route.GetError())
+
+			var match mux.RouteMatch
+			assert.Equal(t, tc.want, route.Match(httptest.NewRequest(http.MethodGet, tc.path, nil), &match))
+			if tc.wantVars != nil {
+				assert.Equal(t, tc.wantVars, match.Vars)
+			}
+		})
+	}
+}
+
+func TestImposterHandler_Templates(t *testing.T) {
+	headers := map[string]HeaderValues{"Location": {"/gophers/{{ .PathParams.id }}"}}
+	imposter := Imposter{
+		Request: Request{Method: http.MethodGet, Endpoint: "/gophers/{id:int}"},
+		Response: Responses{{
+			Status:   http.StatusOK,
+			Headers:  &headers,
+			Body:     `{"id": {{ .PathParams.id }}, "page": "{{ .QueryParams.Get "page" }}", "agent": "{{ .Headers.Get "User-Agent" }}", "session": "{{ .Cookies.session }}"}`,
+			Template: true,
+		}},
+	}
+	require.NoError(t, imposter.Response[0].parseTemplates())
+
+	router := mux.NewRouter()
+	router.HandleFunc(expandEndpoint(imposter.Request.Endpoint), ImposterHandler(imposter))
+
+	req := httptest.NewRequest(http.MethodGet, "/gophers/42?page=2", nil)
+	req.Header.Set("User-Agent", "killgrave")
+	req.Header.Set("Cookie", "session=abc")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "/gophers/42", rec.Header().Get("Location"))
+	assert.JSONEq(t, `{"id": 42, "page": "2", "agent": "killgrave", "session": "abc"}`, rec.Body.String())
+}
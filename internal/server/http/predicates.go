@@ -0,0 +1,345 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/gorilla/mux"
+)
+
+const (
+	predicateFieldPath         = "path"
+	predicateFieldMethod       = "method"
+	predicateFieldBody         = "body"
+	predicateFieldBodyPrefix   = "body:"
+	predicateFieldHeaderPrefix = "header:"
+	predicateFieldQueryPrefix  = "query:"
+	predicateFieldCookiePrefix = "cookie:"
+	predicateFieldXPathPrefix  = "xpath:"
+)
+
+var errInvalidPredicate = errors.New("invalid predicate")
+
+// Predicate is a node of a tree of conditions on the request. A node either combines other
+// predicates with And, Or or Not, or checks a Field of the request with one operator
+// (Equals, Contains, Matches, Exists or Absent).
+//
+// The Field can be path, method, body, body:<json pointer>, xpath:<expression>, header:<name>,
+// query:<name> or cookie:<name>. When a field has several values, like a repeated header or the
+// nodes selected by an XPath expression, it's enough that one of them satisfies the operator.
+// The prefixes of the XPath expressions are resolved with the namespaces of the request,
+// or compared as they're written on the body when the request has no namespaces
+type Predicate struct {
+	And []Predicate `json:"and,omitempty" yaml:"and,omitempty"`
+	Or  []Predicate `json:"or,omitempty" yaml:"or,omitempty"`
+	Not *Predicate  `json:"not,omitempty" yaml:"not,omitempty"`
+
+	Field    string  `json:"field,omitempty" yaml:"field,omitempty"`
+	Equals   *string `json:"equals,omitempty" yaml:"equals,omitempty"`
+	Contains *string `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Matches  *string `json:"matches,omitempty" yaml:"matches,omitempty"`
+	Exists   bool    `json:"exists,omitempty" yaml:"exists,omitempty"`
+	Absent   bool    `json:"absent,omitempty" yaml:"absent,omitempty"`
+
+	matches *regexp.Regexp
+	xpath   *xpath.Expr
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the predicate.
+func (p *Predicate) UnmarshalJSON(data []byte) error {
+	type predicate Predicate
+	if err := json.Unmarshal(data, (*predicate)(p)); err != nil {
+		return err
+	}
+	return p.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the predicate.
+func (p *Predicate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type predicate Predicate
+	if err := unmarshal((*predicate)(p)); err != nil {
+		return err
+	}
+	return p.validate()
+}
+
+// validate checks the node itself, the children are validated when they're unmarshalled
+func (p *Predicate) validate() error {
+	nodes := 0
+	for _, ok := range []bool{p.And != nil, p.Or != nil, p.Not != nil, p.Field != ""} {
+		if ok {
+			nodes++
+		}
+	}
+	if nodes != 1 {
+		return fmt.Errorf("%w: each predicate must have one of and, or, not or field", errInvalidPredicate)
+	}
+
+	if p.Field == "" {
+		return nil
+	}
+
+	if !validPredicateField(p.Field) {
+		return fmt.Errorf("%w: unknown field %q", errInvalidPredicate, p.Field)
+	}
+
+	operators := 0
+	for _, ok := range []bool{p.Equals != nil, p.Contains != nil, p.Matches != nil, p.Exists, p.Absent} {
+		if ok {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return fmt.Errorf("%w: the field %s must have one of equals, contains, matches, exists or absent", errInvalidPredicate, p.Field)
+	}
+
+	if p.Matches != nil {
+		re, err := regexp.Compile(*p.Matches)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidPredicate, err)
+		}
+		p.matches = re
+	}
+
+	return p.compileXPath(nil)
+}
+
+// compileXPath compiles the XPath expression of the field and of the children, resolving their
+// prefixes with the namespaces, which are known once the predicates of the request are unmarshalled
+func (p *Predicate) compileXPath(namespaces map[string]string) error {
+	for i := range p.And {
+		if err := p.And[i].compileXPath(namespaces); err != nil {
+			return err
+		}
+	}
+	for i := range p.Or {
+		if err := p.Or[i].compileXPath(namespaces); err != nil {
+			return err
+		}
+	}
+	if p.Not != nil {
+		return p.Not.compileXPath(namespaces)
+	}
+
+	expr, ok := strings.CutPrefix(p.Field, predicateFieldXPathPrefix)
+	if !ok {
+		return nil
+	}
+
+	compiled, err := xpath.CompileWithNS(expr, namespaces)
+	if err != nil {
+		return fmt.Errorf("%w: invalid xpath %q: %v", errInvalidPredicate, expr, err)
+	}
+	p.xpath = compiled
+	return nil
+}
+
+func validPredicateField(field string) bool {
+	switch field {
+	case predicateFieldPath, predicateFieldMethod, predicateFieldBody:
+		return true
+	}
+
+	for _, prefix := range []string{predicateFieldBodyPrefix, predicateFieldXPathPrefix, predicateFieldHeaderPrefix, predicateFieldQueryPrefix, predicateFieldCookiePrefix} {
+		if strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the predicate, as it's shown by the diagnostics
+func (p Predicate) String() string {
+	switch {
+	case p.And != nil:
+		return joinPredicates("and", p.And)
+	case p.Or != nil:
+		return joinPredicates("or", p.Or)
+	case p.Not != nil:
+		return "not(" + p.Not.String() + ")"
+	case p.Equals != nil:
+		return fmt.Sprintf("%s equals %q", p.Field, *p.Equals)
+	case p.Contains != nil:
+		return fmt.Sprintf("%s contains %q", p.Field, *p.Contains)
+	case p.Matches != nil:
+		return fmt.Sprintf("%s matches %q", p.Field, *p.Matches)
+	case p.Exists:
+		return p.Field + " exists"
+	case p.Absent:
+		return p.Field + " absent"
+	}
+	return ""
+}
+
+func joinPredicates(op string, predicates []Predicate) string {
+	desc := make([]string, len(predicates))
+	for i, p := range predicates {
+		desc[i] = p.String()
+	}
+	return op + "(" + strings.Join(desc, ", ") + ")"
+}
+
+// MatcherByPredicates check if the request satisfies all the predicates of the imposter
+func MatcherByPredicates(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		if len(imposter.Request.Predicates) == 0 {
+			return true
+		}
+
+		pr := newPredicateRequest(req)
+		for _, p := range imposter.Request.Predicates {
+			if !p.evaluate(pr) {
+				loggerFromContext(req.Context()).Debug("request does not match the predicates",
+					"imposter", imposter.Path,
+					"method", imposter.Request.Method,
+					"endpoint", imposter.Request.Endpoint,
+					"predicate", p.String())
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// predicateRequest is the request evaluated by the predicates, its body is read only if a predicate needs it
+type predicateRequest struct {
+	req      *http.Request
+	body     []byte
+	bodyRead bool
+	doc      interface{}
+	docErr   error
+	docRead  bool
+	xmlDoc   *xmlNode
+	xmlErr   error
+	xmlRead  bool
+}
+
+func newPredicateRequest(req *http.Request) *predicateRequest {
+	return &predicateRequest{req: req}
+}
+
+func (pr *predicateRequest) readBody() []byte {
+	if pr.bodyRead {
+		return pr.body
+	}
+	pr.bodyRead = true
+	pr.body, _ = readBody(pr.req)
+	return pr.body
+}
+
+func (pr *predicateRequest) readJSON() (interface{}, error) {
+	if !pr.docRead {
+		pr.docRead = true
+		pr.docErr = json.Unmarshal(pr.readBody(), &pr.doc)
+	}
+	return pr.doc, pr.docErr
+}
+
+func (pr *predicateRequest) readXML() (*xmlNode, error) {
+	if !pr.xmlRead {
+		pr.xmlRead = true
+		pr.xmlDoc, pr.xmlErr = parseXML(pr.readBody())
+	}
+	return pr.xmlDoc, pr.xmlErr
+}
+
+// values returns the values of the field of the predicate on the request, none if it's absent
+func (pr *predicateRequest) values(p Predicate) []string {
+	field := p.Field
+	switch {
+	case field == predicateFieldPath:
+		return []string{pr.req.URL.Path}
+	case field == predicateFieldMethod:
+		return []string{pr.req.Method}
+	case field == predicateFieldBody:
+		if body := pr.readBody(); len(body) > 0 {
+			return []string{string(body)}
+		}
+		return nil
+	case strings.HasPrefix(field, predicateFieldBodyPrefix):
+		doc, err := pr.readJSON()
+		if err != nil {
+			return nil
+		}
+		v, err := jsonGet(doc, strings.TrimPrefix(field, predicateFieldBodyPrefix))
+		if err != nil {
+			return nil
+		}
+		return []string{jsonValueString(v)}
+	case strings.HasPrefix(field, predicateFieldXPathPrefix):
+		doc, err := pr.readXML()
+		if err != nil || p.xpath == nil {
+			return nil
+		}
+		return xpathValues(doc, p.xpath)
+	case strings.HasPrefix(field, predicateFieldHeaderPrefix):
+		return pr.req.Header.Values(strings.TrimPrefix(field, predicateFieldHeaderPrefix))
+	case strings.HasPrefix(field, predicateFieldQueryPrefix):
+		return pr.req.URL.Query()[strings.TrimPrefix(field, predicateFieldQueryPrefix)]
+	case strings.HasPrefix(field, predicateFieldCookiePrefix):
+		return cookieValues(pr.req, strings.TrimPrefix(field, predicateFieldCookiePrefix))
+	}
+	return nil
+}
+
+// jsonValueString returns the strings as they are, and the rest of the values encoded as JSON
+func jsonValueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func (p Predicate) evaluate(pr *predicateRequest) bool {
+	switch {
+	case p.And != nil:
+		for _, child := range p.And {
+			if !child.evaluate(pr) {
+				return false
+			}
+		}
+		return true
+	case p.Or != nil:
+		for _, child := range p.Or {
+			if child.evaluate(pr) {
+				return true
+			}
+		}
+		return false
+	case p.Not != nil:
+		return !p.Not.evaluate(pr)
+	}
+
+	values := pr.values(p)
+	switch {
+	case p.Exists:
+		return len(values) > 0
+	case p.Absent:
+		return len(values) == 0
+	}
+
+	for _, v := range values {
+		if p.evaluateValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Predicate) evaluateValue(v string) bool {
+	switch {
+	case p.Equals != nil:
+		return v == *p.Equals
+	case p.Contains != nil:
+		return strings.Contains(v, *p.Contains)
+	case p.matches != nil:
+		return p.matches.MatchString(v)
+	}
+	return false
+}
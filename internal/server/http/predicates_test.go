@@ -0,0 +1,196 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPredicate_Unmarshal(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"valid leaf":           {input: `{"field": "header:Authorization", "absent": true}`},
+		"valid tree":           {input: `{"or": [{"field": "query:sort", "matches": "^(asc|desc)$"}, {"not": {"field": "body", "contains": "admin"}}]}`},
+		"empty predicate":      {input: `{}`, err: errInvalidPredicate},
+		"field and or":         {input: `{"field": "path", "equals": "/gophers", "or": []}`, err: errInvalidPredicate},
+		"unknown field":        {input: `{"field": "status", "equals": "200"}`, err: errInvalidPredicate},
+		"field without name":   {input: `{"field": "header:", "exists": true}`, err: errInvalidPredicate},
+		"without operator":     {input: `{"field": "path"}`, err: errInvalidPredicate},
+		"two operators":        {input: `{"field": "path", "equals": "/gophers", "contains": "go"}`, err: errInvalidPredicate},
+		"invalid pattern":      {input: `{"field": "path", "matches": "(go"}`, err: errInvalidPredicate},
+		"valid xpath":          {input: `{"field": "xpath://gop:id", "equals": "1"}`},
+		"invalid xpath":        {input: `{"field": "xpath://gop:id[", "exists": true}`, err: errInvalidPredicate},
+		"invalid nested child": {input: `{"not": {"and": [{"field": "path"}]}}`, err: errInvalidPredicate},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var p Predicate
+			err := json.Unmarshal([]byte(tc.input), &p)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPredicate_UnmarshalYAML(t *testing.T) {
+	var p Predicate
+	err := yaml.Unmarshal([]byte(`
+or:
+  - field: header:Authorization
+    absent: true
+  - not:
+      field: header:Authorization
+      matches: "^Bearer "
+`), &p)
+	require.NoError(t, err)
+	assert.Equal(t, `or(header:Authorization absent, not(header:Authorization matches "^Bearer "))`, p.String())
+
+	err = yaml.Unmarshal([]byte("field: cookie:session\n"), &p)
+	assert.True(t, errors.Is(err, errInvalidPredicate))
+}
+
+func TestMatcherByPredicates(t *testing.T) {
+	testCases := map[string]struct {
+		predicates string
+		request    func() *http.Request
+		want       bool
+	}{
+		"header absent": {
+			predicates: `[{"field": "header:Authorization", "absent": true}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers", nil) },
+			want:       true,
+		},
+		"header present when it must be absent": {
+			predicates: `[{"field": "header:Authorization", "absent": true}]`,
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+				req.Header.Set("Authorization", "Bearer token")
+				return req
+			},
+		},
+		"param not equal": {
+			predicates: `[{"not": {"field": "query:status", "equals": "deleted"}}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers?status=active", nil) },
+			want:       true,
+		},
+		"param equal when it must not": {
+			predicates: `[{"not": {"field": "query:status", "equals": "deleted"}}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers?status=deleted", nil) },
+		},
+		"any repeated param": {
+			predicates: `[{"field": "query:tag", "equals": "go"}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers?tag=rust&tag=go", nil) },
+			want:       true,
+		},
+		"either of two headers": {
+			predicates: `[{"or": [{"field": "header:X-Api-Key", "exists": true}, {"field": "cookie:session", "exists": true}]}]`,
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+				req.Header.Set("Cookie", "session=abc123")
+				return req
+			},
+			want: true,
+		},
+		"none of two headers": {
+			predicates: `[{"or": [{"field": "header:X-Api-Key", "exists": true}, {"field": "cookie:session", "exists": true}]}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers", nil) },
+		},
+		"path and method": {
+			predicates: `[{"and": [{"field": "path", "matches": "^/gophers/[0-9]+$"}, {"field": "method", "equals": "DELETE"}]}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodDelete, "/gophers/42", nil) },
+			want:       true,
+		},
+		"body contains": {
+			predicates: `[{"field": "body", "contains": "\"premium\": true"}]`,
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"name": "Zebediah", "premium": true}`))
+			},
+			want: true,
+		},
+		"body json pointer": {
+			predicates: `[{"field": "body:/owner/age", "equals": "42"}, {"field": "body:/owner/name", "matches": "^Z"}]`,
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"owner": {"name": "Zebediah", "age": 42}}`))
+			},
+			want: true,
+		},
+		"body json pointer absent": {
+			predicates: `[{"field": "body:/owner", "absent": true}]`,
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"name": "Zebediah"}`))
+			},
+			want: true,
+		},
+		"all the predicates must match": {
+			predicates: `[{"field": "query:status", "equals": "active"}, {"field": "header:X-Api-Key", "exists": true}]`,
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/gophers?status=active", nil) },
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var predicates []Predicate
+			require.NoError(t, json.Unmarshal([]byte(tc.predicates), &predicates))
+
+			imposter := Imposter{Request: Request{Method: http.MethodGet, Endpoint: "/gophers", Predicates: predicates}}
+			assert.Equal(t, tc.want, MatcherByPredicates(imposter)(tc.request(), nil))
+		})
+	}
+}
+
+func TestMatcherByPredicates_KeepsBody(t *testing.T) {
+	contains := "Zebediah"
+	predicates := []Predicate{{Field: "body", Contains: &contains}}
+	imposter := Imposter{Request: Request{Method: http.MethodPost, Endpoint: "/gophers", Predicates: predicates}}
+
+	req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"name": "Zebediah"}`))
+	assert.True(t, MatcherByPredicates(imposter)(req, nil))
+
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "Zebediah"}`, string(body))
+}
+
+func TestServer_Predicates_MissingAuthorization(t *testing.T) {
+	var imposters []Imposter
+	err := json.Unmarshal([]byte(`[
+		{
+			"request": {"method": "GET", "endpoint": "/gophers", "predicates": [{"field": "header:Authorization", "absent": true}]},
+			"response": {"status": 401, "body": "{\"error\": \"missing credentials\"}"}
+		},
+		{
+			"request": {"method": "GET", "endpoint": "/gophers", "headers": {"Authorization": "^Bearer "}},
+			"response": {"status": 200}
+		}
+	]`), &imposters)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	srv := NewServer(router, &http.Server{Handler: router}, &Proxy{}, false, ImposterFs{})
+	srv.addImposterHandler(imposters)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
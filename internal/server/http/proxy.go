@@ -1,38 +1,296 @@
 package http
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	killgrave "github.com/friendsofgo/killgrave/internal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Proxy represent reverse proxy server.
 type Proxy struct {
-	server *httputil.ReverseProxy
-	mode   killgrave.ProxyMode
-	url    *url.URL
+	mode       killgrave.ProxyMode
+	upstream   *proxyUpstream
+	rules      []proxyRule
+	transforms []proxyTransform
+}
+
+// ProxyOpt function to configure the proxy
+type ProxyOpt func(p *Proxy) error
+
+// proxyRule sends the requests that match its host and path prefix to its upstream
+type proxyRule struct {
+	host       string
+	pathPrefix string
+	upstream   *proxyUpstream
+}
+
+// proxyUpstream represent each one of the servers where the requests are proxied to
+type proxyUpstream struct {
+	server          *httputil.ReverseProxy
+	url             *url.URL
+	stripPrefix     string
+	rewrite         *regexp.Regexp
+	replacement     string
+	requestHeaders  killgrave.ConfigHeaderRules
+	responseHeaders killgrave.ConfigHeaderRules
+	timeout         time.Duration
 }
 
 // NewProxy creates new proxy server.
-func NewProxy(rawurl string, mode killgrave.ProxyMode) (*Proxy, error) {
-	u, err := url.Parse(rawurl)
+func NewProxy(rawurl string, mode killgrave.ProxyMode, opts ...ProxyOpt) (*Proxy, error) {
+	p := &Proxy{mode: mode}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if rawurl != "" || len(p.rules) == 0 {
+		upstream, err := newProxyUpstream(killgrave.ConfigProxyRule{Url: rawurl})
+		if err != nil {
+			return nil, err
+		}
+		p.upstream = upstream
+	}
+
+	return p, nil
+}
+
+// WithProxyRules sends the requests that match any of the rules to the url of the rule,
+// instead of the url of the proxy
+func WithProxyRules(rules ...killgrave.ConfigProxyRule) ProxyOpt {
+	return func(p *Proxy) error {
+		for _, rule := range rules {
+			upstream, err := newProxyUpstream(rule)
+			if err != nil {
+				return err
+			}
+
+			p.rules = append(p.rules, proxyRule{
+				host:       strings.ToLower(rule.Host),
+				pathPrefix: rule.PathPrefix,
+				upstream:   upstream,
+			})
+		}
+		return nil
+	}
+}
+
+func newProxyUpstream(rule killgrave.ConfigProxyRule) (*proxyUpstream, error) {
+	u, err := url.Parse(rule.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream := &proxyUpstream{
+		server:          httputil.NewSingleHostReverseProxy(u),
+		url:             u,
+		requestHeaders:  rule.RequestHeaders,
+		responseHeaders: rule.ResponseHeaders,
+	}
+
+	if rule.StripPrefix {
+		upstream.stripPrefix = strings.TrimSuffix(rule.PathPrefix, "/")
+	}
+
+	if rule.RewritePath != nil {
+		upstream.rewrite, err = regexp.Compile(rule.RewritePath.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid rewrite pattern of the proxy rule for %s", err, rule.Url)
+		}
+		upstream.replacement = rule.RewritePath.Replacement
+	}
+
+	if rule.Timeout != "" {
+		upstream.timeout, err = time.ParseDuration(rule.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid timeout of the proxy rule for %s", err, rule.Url)
+		}
+	}
+
+	if rule.TLS.InsecureSkipVerify || rule.TLS.CAFile != "" {
+		tlsConfig, err := newProxyTLSConfig(rule.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid tls options of the proxy rule for %s", err, rule.Url)
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		upstream.server.Transport = transport
+	}
+
+	upstream.server.ModifyResponse = func(res *http.Response) error {
+		applyHeaderRules(res.Header, upstream.responseHeaders)
+		return transformResponse(res)
+	}
+	upstream.server.ErrorHandler = proxyErrorHandler
+
+	return upstream, nil
+}
+
+func newProxyTLSConfig(cfg killgrave.ConfigProxyTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	ca, err := os.ReadFile(cfg.CAFile)
 	if err != nil {
 		return nil, err
 	}
-	reverseProxy := httputil.NewSingleHostReverseProxy(u)
-	return &Proxy{server: reverseProxy, mode: mode, url: u}, nil
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found on %s", cfg.CAFile)
+	}
+
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
 }
 
 // Handler returns handler that sends request to another server.
 func (p *Proxy) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		r.URL.Host = p.url.Host
-		r.URL.Scheme = p.url.Scheme
-		r.Header.Set("X-Forwarded-Host", r.Header.Get("Host"))
-		r.Host = p.url.Host
+		setProxied(r)
+
+		upstream := p.upstreamFor(r)
+		if upstream == nil {
+			http.Error(w, "no proxy rule matches the request", http.StatusBadGateway)
+			return
+		}
+
+		if t := p.transformFor(r); t != nil {
+			r = withTransform(r, t)
+		}
+
+		upstream.serveHTTP(w, r)
+	}
+}
+
+// upstreamFor returns the upstream of the first rule that matches the request,
+// or the default one if none of them matches
+func (p *Proxy) upstreamFor(r *http.Request) *proxyUpstream {
+	for _, rule := range p.rules {
+		if rule.matches(r) {
+			return rule.upstream
+		}
+	}
+	return p.upstream
+}
+
+func (rule proxyRule) matches(r *http.Request) bool {
+	if rule.host != "" && !matchesHost(rule.host, r.Host) {
+		return false
+	}
+	return matchesPathPrefix(r.URL.Path, rule.pathPrefix)
+}
+
+// matchesPathPrefix checks that the path starts with the whole segments of the prefix,
+// so /users matches /users and /users/1 but not /usersettings
+func matchesPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+// matchesHost checks the host of the request, without port, against the host
+// of the rule, that can start with a wildcard like *.example.com
+func matchesHost(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+func (u *proxyUpstream) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	r.URL.Host = u.url.Host
+	r.URL.Scheme = u.url.Scheme
+	r.Header.Set("X-Forwarded-Host", r.Header.Get("Host"))
+	r.Host = u.url.Host
+
+	if u.stripPrefix != "" {
+		r.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, u.stripPrefix), "/")
+		r.URL.RawPath = ""
+	}
+
+	if u.rewrite != nil {
+		r.URL.Path = u.rewrite.ReplaceAllString(r.URL.Path, u.replacement)
+		r.URL.RawPath = ""
+	}
+
+	applyHeaderRules(r.Header, u.requestHeaders)
+
+	if u.timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), u.timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	if trace.SpanContextFromContext(r.Context()).IsValid() {
+		ctx, span := startSpan(r, "killgrave.proxy", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("killgrave.proxy.url", u.url.String())))
+		defer span.End()
+
+		r = r.WithContext(ctx)
+		tracePropagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+	}
+
+	u.server.ServeHTTP(w, r)
+}
+
+// applyHeaderRules removes, sets and adds the headers, in that order
+func applyHeaderRules(h http.Header, rules killgrave.ConfigHeaderRules) {
+	for _, k := range rules.Remove {
+		h.Del(k)
+	}
+
+	for k, v := range rules.Set {
+		h.Set(k, v)
+	}
+
+	for k, v := range rules.Add {
+		h.Add(k, v)
+	}
+}
+
+// proxyErrorHandler responds with a 504 when the proxied server exceeds the timeout, and with a 502 otherwise
+func proxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errConnectionReset) {
+		resetConnection(w)
+		return
+	}
+
+	loggerFromContext(r.Context()).Warn("error proxying the request", "url", r.URL.String(), "error", err)
 
-		p.server.ServeHTTP(w, r)
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
 	}
+	w.WriteHeader(http.StatusBadGateway)
 }
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	killgrave "github.com/friendsofgo/killgrave/internal"
 	"github.com/stretchr/testify/assert"
@@ -52,3 +54,245 @@ func TestProxyHandler(t *testing.T) {
 	assert.True(t, isRequestHandled)
 
 }
+
+func TestProxyHandler_Rules(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Backend", name)
+			w.Header().Set("X-Internal", "secret")
+			w.Header().Set("X-Path", r.URL.Path)
+			w.Header().Set("X-Token", r.Header.Get("X-Token"))
+			w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		}))
+	}
+
+	users := newBackend("users")
+	defer users.Close()
+	orders := newBackend("orders")
+	defer orders.Close()
+	fallback := newBackend("default")
+	defer fallback.Close()
+
+	proxy, err := NewProxy(fallback.URL, killgrave.ProxyAll, WithProxyRules(
+		killgrave.ConfigProxyRule{
+			PathPrefix:  "/users",
+			Url:         users.URL,
+			StripPrefix: true,
+			RequestHeaders: killgrave.ConfigHeaderRules{
+				Remove: []string{"Cookie"},
+				Set:    map[string]string{"X-Token": "abc"},
+			},
+			ResponseHeaders: killgrave.ConfigHeaderRules{
+				Remove: []string{"X-Internal"},
+				Add:    map[string]string{"X-Proxied-By": "killgrave"},
+			},
+		},
+		killgrave.ConfigProxyRule{
+			Host:        "*.orders.local",
+			Url:         orders.URL,
+			RewritePath: &killgrave.ConfigRewrite{Pattern: `^/v1/(.*)$`, Replacement: "/api/$1"},
+		},
+	))
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		host        string
+		path        string
+		wantBackend string
+		wantPath    string
+		wantHeaders map[string]string
+	}{
+		"path prefix with strip prefix and header rules": {
+			path:        "/users/1",
+			wantBackend: "users",
+			wantPath:    "/1",
+			wantHeaders: map[string]string{"X-Token": "abc", "X-Cookie": "", "X-Internal": "", "X-Proxied-By": "killgrave"},
+		},
+		"path prefix without the rest of the path": {
+			path:        "/users",
+			wantBackend: "users",
+			wantPath:    "/",
+		},
+		"path starting with the path prefix": {
+			path:        "/usersettings",
+			wantBackend: "default",
+			wantPath:    "/usersettings",
+		},
+		"host with wildcard and path rewrite": {
+			host:        "eu.orders.local:3000",
+			path:        "/v1/orders",
+			wantBackend: "orders",
+			wantPath:    "/api/orders",
+			wantHeaders: map[string]string{"X-Internal": "secret", "X-Cookie": "session=1"},
+		},
+		"no rule matches": {
+			host:        "orders.local",
+			path:        "/v1/orders",
+			wantBackend: "default",
+			wantPath:    "/v1/orders",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			req.Header.Set("Cookie", "session=1")
+			rec := httptest.NewRecorder()
+
+			proxy.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.wantBackend, rec.Header().Get("X-Backend"))
+			assert.Equal(t, tc.wantPath, rec.Header().Get("X-Path"))
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_NoUpstream(t *testing.T) {
+	proxy, err := NewProxy("", killgrave.ProxyMissing, WithProxyRules(killgrave.ConfigProxyRule{PathPrefix: "/users", Url: "http://localhost"}))
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	proxy.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestProxyHandler_Timeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer backend.Close()
+
+	proxy, err := NewProxy("", killgrave.ProxyAll, WithProxyRules(killgrave.ConfigProxyRule{Url: backend.URL, Timeout: "10ms"}))
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	proxy.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+}
+
+func TestProxyHandler_TLS(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+
+	testCases := map[string]struct {
+		tls        killgrave.ConfigProxyTLS
+		wantStatus int
+	}{
+		"untrusted certificate": {wantStatus: http.StatusBadGateway},
+		"skip verify":           {tls: killgrave.ConfigProxyTLS{InsecureSkipVerify: true}, wantStatus: http.StatusNoContent},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			proxy, err := NewProxy("", killgrave.ProxyAll, WithProxyRules(killgrave.ConfigProxyRule{Url: backend.URL, TLS: tc.tls}))
+			assert.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			proxy.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestProxyHandler_Transforms(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Internal", "secret")
+		w.Write([]byte(`{"id":1,"name":"Gopher","tags":["a"]}`))
+	}))
+	defer backend.Close()
+
+	proxy, err := NewProxy(backend.URL, killgrave.ProxyAll, WithProxyTransforms(
+		killgrave.ConfigProxyTransform{
+			Method:  http.MethodGet,
+			Path:    "/users/{id}",
+			Status:  http.StatusAccepted,
+			Headers: killgrave.ConfigHeaderRules{Remove: []string{"X-Internal"}, Set: map[string]string{"X-Transformed": "true"}},
+			MergePatch: map[interface{}]interface{}{
+				"name": "Killgrave",
+			},
+			JSONPatch: []killgrave.ConfigJSONPatchOp{
+				{Op: "add", Path: "/tags/-", Value: "b"},
+				{Op: "remove", Path: "/id"},
+			},
+		},
+		killgrave.ConfigProxyTransform{Path: "/empty", Status: http.StatusServiceUnavailable, Fault: killgrave.FaultEmptyBody},
+		killgrave.ConfigProxyTransform{Path: "/reset", Fault: killgrave.FaultConnectionReset},
+	))
+	assert.NoError(t, err)
+
+	frontend := httptest.NewServer(proxy.Handler())
+	defer frontend.Close()
+
+	testCases := map[string]struct {
+		method      string
+		path        string
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		"patched response": {
+			method:      http.MethodGet,
+			path:        "/users/1",
+			wantStatus:  http.StatusAccepted,
+			wantBody:    `{"name":"Killgrave","tags":["a","b"]}`,
+			wantHeaders: map[string]string{"X-Internal": "", "X-Transformed": "true"},
+		},
+		"method not matching": {
+			method:      http.MethodPost,
+			path:        "/users/1",
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":1,"name":"Gopher","tags":["a"]}`,
+			wantHeaders: map[string]string{"X-Internal": "secret"},
+		},
+		"empty body fault": {
+			method:     http.MethodGet,
+			path:       "/empty",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, frontend.URL+tc.path, nil)
+			assert.NoError(t, err)
+
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			if tc.wantBody == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tc.wantBody, string(body))
+			}
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, res.Header.Get(k), k)
+			}
+		})
+	}
+
+	t.Run("connection reset fault", func(t *testing.T) {
+		_, err := http.Get(frontend.URL + "/reset")
+		assert.Error(t, err)
+	})
+}
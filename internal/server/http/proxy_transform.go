@@ -0,0 +1,210 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+)
+
+type proxyTransformCtxKey struct{}
+
+// errConnectionReset makes the proxy close the connection without responding
+var errConnectionReset = errors.New("connection reset by proxy fault")
+
+// proxyTransform modifies the proxied responses of the requests that match its route
+type proxyTransform struct {
+	route      *mux.Route
+	status     int
+	headers    killgrave.ConfigHeaderRules
+	overrides  http.Header
+	mergePatch interface{}
+	jsonPatch  []jsonPatchOp
+	delay      ResponseDelay
+	fault      killgrave.ProxyFault
+}
+
+// WithProxyTransforms modifies the proxied responses of the requests that match any of the transforms,
+// when a request matches several of them only the first one is applied
+func WithProxyTransforms(transforms ...killgrave.ConfigProxyTransform) ProxyOpt {
+	return func(p *Proxy) error {
+		for _, cfg := range transforms {
+			t, err := newProxyTransform(cfg)
+			if err != nil {
+				return fmt.Errorf("%w: invalid proxy transform of %s %s", err, cfg.Method, cfg.Path)
+			}
+			p.transforms = append(p.transforms, t)
+		}
+		return nil
+	}
+}
+
+func newProxyTransform(cfg killgrave.ConfigProxyTransform) (proxyTransform, error) {
+	t := proxyTransform{
+		route:   mux.NewRouter().NewRoute(),
+		status:  cfg.Status,
+		headers: cfg.Headers,
+		fault:   cfg.Fault,
+	}
+
+	if cfg.Path != "" {
+		t.route.Path(expandEndpoint(cfg.Path))
+	}
+	if cfg.Method != "" {
+		t.route.Methods(cfg.Method)
+	}
+	if err := t.route.GetError(); err != nil {
+		return proxyTransform{}, err
+	}
+
+	if err := t.delay.parseDelay(cfg.Delay); err != nil {
+		return proxyTransform{}, err
+	}
+
+	if cfg.MergePatch != nil {
+		patch, err := toJSONValue(cfg.MergePatch)
+		if err != nil {
+			return proxyTransform{}, err
+		}
+		t.mergePatch = patch
+	}
+
+	for _, op := range cfg.JSONPatch {
+		value, err := toJSONValue(op.Value)
+		if err != nil {
+			return proxyTransform{}, err
+		}
+		t.jsonPatch = append(t.jsonPatch, jsonPatchOp{Op: op.Op, Path: op.Path, From: op.From, Value: value})
+	}
+
+	return t, nil
+}
+
+// transformFor returns the first transform that matches the request, if any
+func (p *Proxy) transformFor(r *http.Request) *proxyTransform {
+	for i := range p.transforms {
+		if matchesRoute(p.transforms[i].route, r) {
+			return &p.transforms[i]
+		}
+	}
+	return nil
+}
+
+// withTransform makes the transform available to modify the proxied response of the request
+func withTransform(r *http.Request, t *proxyTransform) *http.Request {
+	if t.patchesBody() {
+		// the body can't be patched if the proxied server compresses it
+		r.Header.Del("Accept-Encoding")
+	}
+	return r.WithContext(context.WithValue(r.Context(), proxyTransformCtxKey{}, t))
+}
+
+func (t *proxyTransform) patchesBody() bool {
+	return t.mergePatch != nil || len(t.jsonPatch) > 0
+}
+
+// transformResponse applies the transform of the request, if any, to the proxied response
+func transformResponse(res *http.Response) error {
+	t, ok := res.Request.Context().Value(proxyTransformCtxKey{}).(*proxyTransform)
+	if !ok {
+		return nil
+	}
+
+	if delay := t.delay.Delay(); delay > 0 {
+		setDelay(res.Request, delay)
+		time.Sleep(delay)
+	}
+
+	if t.fault == killgrave.FaultConnectionReset {
+		return errConnectionReset
+	}
+
+	if t.status != 0 {
+		res.StatusCode = t.status
+		res.Status = fmt.Sprintf("%d %s", t.status, http.StatusText(t.status))
+	}
+
+	applyHeaderRules(res.Header, t.headers)
+	for k, v := range t.overrides {
+		res.Header[k] = v
+	}
+
+	switch {
+	case t.fault == killgrave.FaultEmptyBody:
+		res.Body.Close()
+		setResponseBody(res, nil)
+	case t.patchesBody():
+		if err := t.patchBody(res); err != nil {
+			loggerFromContext(res.Request.Context()).Warn("error patching the proxied response",
+				"url", res.Request.URL.String(), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// patchBody applies the merge patch and then the JSON patch to the body of the response,
+// which is left unchanged if it isn't a JSON document
+func (t *proxyTransform) patchBody(res *http.Response) error {
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	setResponseBody(res, body)
+
+	if res.Header.Get("Content-Encoding") != "" {
+		return fmt.Errorf("the body is encoded with %s", res.Header.Get("Content-Encoding"))
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+
+	if t.mergePatch != nil {
+		doc = applyMergePatch(doc, t.mergePatch)
+	}
+
+	if doc, err = applyJSONPatch(doc, t.jsonPatch); err != nil {
+		return err
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	setResponseBody(res, patched)
+	return nil
+}
+
+func setResponseBody(res *http.Response, body []byte) {
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.TransferEncoding = nil
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
+
+// resetConnection closes the client connection without sending any response, or
+// responds with a 502 if the connection can't be taken over
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+var errInvalidQueryParam = errors.New("invalid query param")
+
+// RequestQuery restricts the query parameters of the request, handling the repeated keys.
+// In Strict mode, the request can't have parameters other than the ones of the imposter
+// (also the ones of the Params of the Request)
+type RequestQuery struct {
+	Strict bool                    `json:"strict,omitempty" yaml:"strict,omitempty"`
+	Params map[string]ParamMatcher `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// ParamMatcher restricts the values of a query parameter with one of AnyOf (at least one
+// of the values is present), AllOf (all the values are present), Exact (the values are the
+// same, in any order) or Absent. Without any of them, the parameter only must be present
+type ParamMatcher struct {
+	AnyOf  []string `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
+	AllOf  []string `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+	Exact  []string `json:"exact,omitempty" yaml:"exact,omitempty"`
+	Absent bool     `json:"absent,omitempty" yaml:"absent,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the param matcher.
+func (m *ParamMatcher) UnmarshalJSON(data []byte) error {
+	type paramMatcher ParamMatcher
+	if err := json.Unmarshal(data, (*paramMatcher)(m)); err != nil {
+		return err
+	}
+	return m.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the param matcher.
+func (m *ParamMatcher) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type paramMatcher ParamMatcher
+	if err := unmarshal((*paramMatcher)(m)); err != nil {
+		return err
+	}
+	return m.validate()
+}
+
+func (m *ParamMatcher) validate() error {
+	operators := 0
+	for _, ok := range []bool{m.AnyOf != nil, m.AllOf != nil, m.Exact != nil, m.Absent} {
+		if ok {
+			operators++
+		}
+	}
+	if operators > 1 {
+		return fmt.Errorf("%w: only one of anyOf, allOf, exact or absent can be defined", errInvalidQueryParam)
+	}
+	return nil
+}
+
+// matches checks the values of the parameter on the request
+func (m ParamMatcher) matches(values []string) bool {
+	switch {
+	case m.Absent:
+		return len(values) == 0
+	case m.AnyOf != nil:
+		for _, v := range m.AnyOf {
+			if containsString(values, v) {
+				return true
+			}
+		}
+		return false
+	case m.AllOf != nil:
+		for _, v := range m.AllOf {
+			if !containsString(values, v) {
+				return false
+			}
+		}
+		return len(values) > 0
+	case m.Exact != nil:
+		return sameValues(values, m.Exact)
+	}
+	return len(values) > 0
+}
+
+func (m ParamMatcher) String() string {
+	switch {
+	case m.Absent:
+		return "absent"
+	case m.AnyOf != nil:
+		return "any of " + strings.Join(m.AnyOf, ", ")
+	case m.AllOf != nil:
+		return "all of " + strings.Join(m.AllOf, ", ")
+	case m.Exact != nil:
+		return "exactly " + strings.Join(m.Exact, ", ")
+	}
+	return "present"
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValues checks that both lists have the same values, repeated the same times, in any order
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		counts[v]--
+		if counts[v] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatcherByQuery check if the query parameters of the request matching with the imposter
+func MatcherByQuery(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		err := validateQuery(imposter, req)
+		if err != nil {
+			loggerFromContext(req.Context()).Debug("request does not match the query",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"error", err)
+			return false
+		}
+		return true
+	}
+}
+
+func validateQuery(imposter Imposter, req *http.Request) error {
+	q := imposter.Request.Query
+	if q == nil {
+		return nil
+	}
+
+	query := req.URL.Query()
+	for k, m := range q.Params {
+		if !m.matches(query[k]) {
+			return fmt.Errorf("the query param %s isn't %s", k, m)
+		}
+	}
+
+	if unexpected := unexpectedParams(imposter, req); len(unexpected) > 0 {
+		return fmt.Errorf("unexpected query params %s", strings.Join(unexpected, ", "))
+	}
+	return nil
+}
+
+// unexpectedParams returns the parameters of the request that the imposter doesn't expect in strict mode
+func unexpectedParams(imposter Imposter, req *http.Request) []string {
+	q := imposter.Request.Query
+	if q == nil || !q.Strict {
+		return nil
+	}
+
+	var unexpected []string
+	for k := range req.URL.Query() {
+		if m, ok := q.Params[k]; ok && !m.Absent {
+			continue
+		}
+		if imposter.Request.Params != nil {
+			if _, ok := (*imposter.Request.Params)[k]; ok {
+				continue
+			}
+		}
+		unexpected = append(unexpected, k)
+	}
+
+	sort.Strings(unexpected)
+	return unexpected
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParamMatcher_Unmarshal(t *testing.T) {
+	var m ParamMatcher
+	assert.NoError(t, json.Unmarshal([]byte(`{"anyOf": ["a", "b"]}`), &m))
+	assert.NoError(t, json.Unmarshal([]byte(`{}`), &m))
+
+	err := json.Unmarshal([]byte(`{"anyOf": ["a"], "exact": ["a"]}`), &m)
+	assert.True(t, errors.Is(err, errInvalidQueryParam))
+
+	err = yaml.Unmarshal([]byte("allOf: [a]\nabsent: true\n"), &m)
+	assert.True(t, errors.Is(err, errInvalidQueryParam))
+}
+
+func TestMatcherByQuery(t *testing.T) {
+	testCases := map[string]struct {
+		query  string
+		params *map[string]string
+		url    string
+		want   bool
+	}{
+		"any of":                         {query: `{"params": {"tag": {"anyOf": ["go", "rust"]}}}`, url: "/gophers?tag=java&tag=go", want: true},
+		"none of any of":                 {query: `{"params": {"tag": {"anyOf": ["go", "rust"]}}}`, url: "/gophers?tag=java"},
+		"all of":                         {query: `{"params": {"tag": {"allOf": ["go", "rust"]}}}`, url: "/gophers?tag=rust&tag=java&tag=go", want: true},
+		"missing one of all of":          {query: `{"params": {"tag": {"allOf": ["go", "rust"]}}}`, url: "/gophers?tag=go"},
+		"exact in other order":           {query: `{"params": {"tag": {"exact": ["go", "rust"]}}}`, url: "/gophers?tag=rust&tag=go", want: true},
+		"exact with extra value":         {query: `{"params": {"tag": {"exact": ["go", "rust"]}}}`, url: "/gophers?tag=rust&tag=go&tag=java"},
+		"exact with repeated value":      {query: `{"params": {"tag": {"exact": ["go", "go"]}}}`, url: "/gophers?tag=go&tag=go", want: true},
+		"exact with less repetitions":    {query: `{"params": {"tag": {"exact": ["go", "go"]}}}`, url: "/gophers?tag=go&tag=rust"},
+		"absent":                         {query: `{"params": {"debug": {"absent": true}}}`, url: "/gophers?tag=go", want: true},
+		"present when it must be absent": {query: `{"params": {"debug": {"absent": true}}}`, url: "/gophers?debug=true"},
+		"present":                        {query: `{"params": {"page": {}}}`, url: "/gophers?page=", want: true},
+		"missing":                        {query: `{"params": {"page": {}}}`, url: "/gophers"},
+		"strict":                         {query: `{"strict": true, "params": {"tag": {"anyOf": ["go"]}}}`, url: "/gophers?tag=go", want: true},
+		"strict with unexpected param":   {query: `{"strict": true, "params": {"tag": {"anyOf": ["go"]}}}`, url: "/gophers?tag=go&page=2"},
+		"strict with the params":         {query: `{"strict": true}`, params: &map[string]string{"page": "[0-9]+"}, url: "/gophers?page=2", want: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var query RequestQuery
+			require.NoError(t, json.Unmarshal([]byte(tc.query), &query))
+
+			imposter := Imposter{Request: Request{Method: http.MethodGet, Endpoint: "/gophers", Params: tc.params, Query: &query}}
+			assert.Equal(t, tc.want, MatcherByQuery(imposter)(httptest.NewRequest(http.MethodGet, tc.url, nil), nil))
+		})
+	}
+}
+
+func TestServer_Diagnostics_Query(t *testing.T) {
+	var imposters []Imposter
+	err := json.Unmarshal([]byte(`[{
+		"request": {"method": "GET", "endpoint": "/gophers", "query": {"strict": true, "params": {"tag": {"allOf": ["go", "rust"]}}}},
+		"response": {"status": 200}
+	}]`), &imposters)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	srv := NewServer(router, &http.Server{Handler: router}, &Proxy{}, false, ImposterFs{})
+	srv.addImposterHandler(imposters)
+
+	m := explainMismatch(imposters[0], httptest.NewRequest(http.MethodGet, "/gophers?tag=go&page=2", nil))
+	assert.Equal(t, []predicateFailure{
+		{Predicate: "query", Name: "tag", Expected: "all of go, rust", Actual: "go"},
+		{Predicate: "query", Name: "page", Expected: "absent", Actual: "2"},
+	}, m.Failures)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gophers?tag=go&tag=rust", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
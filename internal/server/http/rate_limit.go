@@ -0,0 +1,272 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm is the algorithm used to limit the requests of an imposter
+type RateLimitAlgorithm string
+
+const (
+	// TokenBucket refills the Limit tokens of the bucket progressively along the Window,
+	// each request takes a token
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+	// FixedWindow allows Limit requests on each Window
+	FixedWindow RateLimitAlgorithm = "fixed_window"
+)
+
+const (
+	rateLimitKeyGlobal       = "global"
+	rateLimitKeyIP           = "ip"
+	rateLimitKeyHeaderPrefix = "header:"
+	rateLimitKeyQueryPrefix  = "query:"
+)
+
+var errInvalidRateLimit = errors.New("invalid rate limit")
+
+// RateLimit limits the requests handled by an imposter. The requests that exceed the limit
+// get the Response, a 429 Too Many Requests by default, instead of the imposter responses
+type RateLimit struct {
+	Algorithm RateLimitAlgorithm `json:"algorithm" yaml:"algorithm"`
+	Limit     int                `json:"limit" yaml:"limit"`
+	Window    Duration           `json:"window" yaml:"window"`
+	Key       string             `json:"key" yaml:"key"`
+	Response  *Response          `json:"response" yaml:"response"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the rate limit.
+func (rl *RateLimit) UnmarshalJSON(data []byte) error {
+	type rateLimit RateLimit
+	if err := json.Unmarshal(data, (*rateLimit)(rl)); err != nil {
+		return err
+	}
+	return rl.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the rate limit.
+func (rl *RateLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rateLimit RateLimit
+	if err := unmarshal((*rateLimit)(rl)); err != nil {
+		return err
+	}
+	return rl.validate()
+}
+
+func (rl *RateLimit) validate() error {
+	switch rl.Algorithm {
+	case "", TokenBucket, FixedWindow:
+	default:
+		return fmt.Errorf("%w: unknown algorithm %q, the options are %s or %s", errInvalidRateLimit, rl.Algorithm, TokenBucket, FixedWindow)
+	}
+
+	if rl.Limit <= 0 {
+		return fmt.Errorf("%w: the limit must be greater than zero", errInvalidRateLimit)
+	}
+
+	if rl.Window <= 0 {
+		return fmt.Errorf("%w: the window must be greater than zero", errInvalidRateLimit)
+	}
+
+	switch {
+	case rl.Key == "", rl.Key == rateLimitKeyGlobal, rl.Key == rateLimitKeyIP:
+	case strings.HasPrefix(rl.Key, rateLimitKeyHeaderPrefix), strings.HasPrefix(rl.Key, rateLimitKeyQueryPrefix):
+	default:
+		return fmt.Errorf("%w: unknown key %q, the options are global, ip, header:<name> or query:<name>", errInvalidRateLimit, rl.Key)
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration written as a string that can be parsed by time.ParseDuration
+type Duration time.Duration
+
+// UnmarshalJSON of json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	return d.parse(input)
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var input string
+	if err := unmarshal(&input); err != nil {
+		return err
+	}
+	return d.parse(input)
+}
+
+func (d *Duration) parse(input string) error {
+	if input == "" {
+		*d = 0
+		return nil
+	}
+
+	duration, err := time.ParseDuration(input)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// rateLimiter keeps the state of the RateLimit of an imposter, for each key. The buckets idle for
+// longer than the window are full again, so they're evicted once per window to not keep every key seen
+type rateLimiter struct {
+	cfg     RateLimit
+	window  time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+	evicted time.Time
+}
+
+// rateLimitBucket is the state of a key, the tokens left and when they were updated
+// for the token bucket, and the requests left and when the window started for the fixed window
+type rateLimitBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimitStatus is the result of taking a request from the limiter
+type rateLimitStatus struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+func newRateLimiter(cfg RateLimit) *rateLimiter {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = TokenBucket
+	}
+
+	if cfg.Response == nil {
+		cfg.Response = &Response{Status: http.StatusTooManyRequests}
+	}
+
+	return &rateLimiter{
+		cfg:     cfg,
+		window:  time.Duration(cfg.Window),
+		now:     time.Now,
+		buckets: make(map[string]*rateLimitBucket),
+	}
+}
+
+// take consumes a request from the limit of the key of the request
+func (rl *rateLimiter) take(r *http.Request) rateLimitStatus {
+	key := rl.key(r)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.evict()
+	if rl.cfg.Algorithm == FixedWindow {
+		return rl.takeFixedWindow(key)
+	}
+	return rl.takeTokenBucket(key)
+}
+
+func (rl *rateLimiter) takeTokenBucket(key string) rateLimitStatus {
+	now := rl.now()
+	limit := float64(rl.cfg.Limit)
+	rate := limit / rl.window.Seconds()
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &rateLimitBucket{tokens: limit, updated: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(limit, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	status := rateLimitStatus{allowed: b.tokens >= 1}
+	if status.allowed {
+		b.tokens--
+	} else {
+		status.retryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+
+	status.remaining = int(b.tokens)
+	status.reset = time.Duration((limit - b.tokens) / rate * float64(time.Second))
+	return status
+}
+
+func (rl *rateLimiter) takeFixedWindow(key string) rateLimitStatus {
+	now := rl.now()
+	start := now.Truncate(rl.window)
+
+	b, ok := rl.buckets[key]
+	if !ok || !b.updated.Equal(start) {
+		b = &rateLimitBucket{tokens: float64(rl.cfg.Limit), updated: start}
+		rl.buckets[key] = b
+	}
+
+	status := rateLimitStatus{allowed: b.tokens >= 1, reset: start.Add(rl.window).Sub(now)}
+	if status.allowed {
+		b.tokens--
+	} else {
+		status.retryAfter = status.reset
+	}
+
+	status.remaining = int(b.tokens)
+	return status
+}
+
+// evict removes the buckets that have been idle for longer than the window, at most once per window
+func (rl *rateLimiter) evict() {
+	now := rl.now()
+	if now.Sub(rl.evicted) < rl.window {
+		return
+	}
+	rl.evicted = now
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.updated) >= rl.window {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// key returns the key of the request whose requests are limited together
+func (rl *rateLimiter) key(r *http.Request) string {
+	switch {
+	case rl.cfg.Key == rateLimitKeyIP:
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	case strings.HasPrefix(rl.cfg.Key, rateLimitKeyHeaderPrefix):
+		return r.Header.Get(strings.TrimPrefix(rl.cfg.Key, rateLimitKeyHeaderPrefix))
+	case strings.HasPrefix(rl.cfg.Key, rateLimitKeyQueryPrefix):
+		return r.URL.Query().Get(strings.TrimPrefix(rl.cfg.Key, rateLimitKeyQueryPrefix))
+	}
+	return rateLimitKeyGlobal
+}
+
+// writeHeaders writes the X-RateLimit-* headers, and the Retry-After header when the limit is exceeded
+func (rl *rateLimiter) writeHeaders(w http.ResponseWriter, status rateLimitStatus) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.cfg.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(status.reset)))
+
+	if !status.allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(status.retryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRateLimit_Unmarshal(t *testing.T) {
+	testCases := map[string]struct {
+		json    string
+		want    RateLimit
+		wantErr bool
+	}{
+		"token bucket by header": {
+			json: `{"limit": 10, "window": "1m", "key": "header:X-Api-Key"}`,
+			want: RateLimit{Limit: 10, Window: Duration(time.Minute), Key: "header:X-Api-Key"},
+		},
+		"fixed window with response": {
+			json: `{"algorithm": "fixed_window", "limit": 1, "window": "1s", "response": {"status": 503}}`,
+			want: RateLimit{Algorithm: FixedWindow, Limit: 1, Window: Duration(time.Second), Response: &Response{Status: 503}},
+		},
+		"unknown algorithm": {json: `{"algorithm": "leaky_bucket", "limit": 1, "window": "1s"}`, wantErr: true},
+		"missing limit":     {json: `{"window": "1s"}`, wantErr: true},
+		"missing window":    {json: `{"limit": 1}`, wantErr: true},
+		"invalid window":    {json: `{"limit": 1, "window": "soon"}`, wantErr: true},
+		"unknown key":       {json: `{"limit": 1, "window": "1s", "key": "cookie:session"}`, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var fromJSON, fromYAML RateLimit
+			jsonErr := json.Unmarshal([]byte(tc.json), &fromJSON)
+			// JSON is valid YAML
+			yamlErr := yaml.Unmarshal([]byte(tc.json), &fromYAML)
+
+			if tc.wantErr {
+				assert.Error(t, jsonErr)
+				assert.Error(t, yamlErr)
+				return
+			}
+
+			assert.NoError(t, jsonErr)
+			assert.NoError(t, yamlErr)
+			assert.Equal(t, tc.want, fromJSON)
+			assert.Equal(t, tc.want, fromYAML)
+		})
+	}
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		algorithm RateLimitAlgorithm
+		steps     []time.Duration
+		want      []bool
+	}{
+		"token bucket refills progressively": {
+			algorithm: TokenBucket,
+			steps:     []time.Duration{0, 0, 0, 500 * time.Millisecond, 0, 500 * time.Millisecond},
+			want:      []bool{true, true, false, true, false, true},
+		},
+		"fixed window resets at the end of the window": {
+			algorithm: FixedWindow,
+			steps:     []time.Duration{0, 0, 0, 500 * time.Millisecond, 500 * time.Millisecond, 0},
+			want:      []bool{true, true, false, false, true, true},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			now := start
+			limiter := newRateLimiter(RateLimit{Algorithm: tc.algorithm, Limit: 2, Window: Duration(time.Second)})
+			limiter.now = func() time.Time { return now }
+
+			for i, step := range tc.steps {
+				now = now.Add(step)
+				status := limiter.take(httptest.NewRequest(http.MethodGet, "/", nil))
+				assert.Equal(t, tc.want[i], status.allowed, "request %d", i)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	for _, algorithm := range []RateLimitAlgorithm{TokenBucket, FixedWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			limiter := newRateLimiter(RateLimit{Algorithm: algorithm, Limit: 2, Window: Duration(time.Second), Key: "header:X-Client"})
+			limiter.now = func() time.Time { return now }
+
+			take := func(client string) rateLimitStatus {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Client", client)
+				return limiter.take(req)
+			}
+
+			for i := 0; i < 100; i++ {
+				take(strconv.Itoa(i))
+			}
+			take("gopher")
+			assert.Len(t, limiter.buckets, 101)
+
+			now = now.Add(500 * time.Millisecond)
+			take("gopher")
+			assert.Len(t, limiter.buckets, 101, "the buckets aren't evicted before the window")
+
+			now = now.Add(time.Second)
+			assert.True(t, take("gopher").allowed)
+			assert.Len(t, limiter.buckets, 1, "the idle buckets must be evicted")
+			assert.Equal(t, 1, take("0").remaining, "an evicted key starts with a full bucket")
+		})
+	}
+}
+
+func TestImposterHandler_RateLimit(t *testing.T) {
+	imposter := Imposter{
+		Request:  Request{Method: http.MethodGet, Endpoint: "/gophers"},
+		Response: Responses{{Status: http.StatusOK, Body: "first"}, {Status: http.StatusOK, Body: "second"}},
+		RateLimit: &RateLimit{
+			Algorithm: FixedWindow,
+			Limit:     1,
+			Window:    Duration(time.Hour),
+			Key:       "header:X-Api-Key",
+		},
+	}
+	handler := ImposterHandler(imposter)
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/gophers", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("gopher")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "first", rec.Body.String())
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	rec = send("gopher")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.NotEmpty(t, rec.Header().Get("X-RateLimit-Reset"))
+
+	// the exceeded requests don't move forward the sequence of responses
+	rec = send("another gopher")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "second", rec.Body.String())
+}
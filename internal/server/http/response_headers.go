@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HeaderValues are the values of a response header, written either as a single string,
+// like the imposters always did, or as a list to repeat the header
+type HeaderValues []string
+
+// UnmarshalJSON of json.Unmarshaler interface, it accepts a string or a list of strings.
+func (hv *HeaderValues) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*hv = HeaderValues{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*hv = values
+	return nil
+}
+
+// MarshalJSON of json.Marshaler interface, a single value is written as a string.
+func (hv HeaderValues) MarshalJSON() ([]byte, error) {
+	if len(hv) == 1 {
+		return json.Marshal(hv[0])
+	}
+	return json.Marshal([]string(hv))
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it accepts a string or a list of strings.
+func (hv *HeaderValues) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*hv = HeaderValues{value}
+		return nil
+	}
+
+	var values []string
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+	*hv = values
+	return nil
+}
+
+// MarshalYAML of yaml.Marshaler interface, a single value is written as a string.
+func (hv HeaderValues) MarshalYAML() (interface{}, error) {
+	if len(hv) == 1 {
+		return hv[0], nil
+	}
+	return []string(hv), nil
+}
+
+// responseHeader returns the headers of the response as an http.Header
+func responseHeader(headers *map[string]HeaderValues) http.Header {
+	h := make(http.Header)
+	if headers == nil {
+		return h
+	}
+
+	for k, values := range *headers {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
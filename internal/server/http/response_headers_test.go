@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestHeaderValues_UnmarshalJSON(t *testing.T) {
+	var res Response
+	err := json.Unmarshal([]byte(`{"status": 200, "headers": {"Content-Type": "application/json", "Link": ["</gophers?page=1>; rel=\"first\"", "</gophers?page=3>; rel=\"next\""]}}`), &res)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]HeaderValues{
+		"Content-Type": {"application/json"},
+		"Link":         {`</gophers?page=1>; rel="first"`, `</gophers?page=3>; rel="next"`},
+	}, *res.Headers)
+
+	b, err := json.Marshal(res.Headers)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Content-Type": "application/json", "Link": ["</gophers?page=1>; rel=\"first\"", "</gophers?page=3>; rel=\"next\""]}`, string(b))
+
+	err = json.Unmarshal([]byte(`{"headers": {"Content-Type": 200}}`), &res)
+	assert.Error(t, err)
+}
+
+func TestHeaderValues_UnmarshalYAML(t *testing.T) {
+	var res Response
+	err := yaml.Unmarshal([]byte(`
+status: 200
+headers:
+  Content-Type: application/json
+  Vary:
+    - Accept
+    - Accept-Encoding
+`), &res)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]HeaderValues{
+		"Content-Type": {"application/json"},
+		"Vary":         {"Accept", "Accept-Encoding"},
+	}, *res.Headers)
+
+	b, err := yaml.Marshal(res.Headers)
+	require.NoError(t, err)
+	assert.Equal(t, "Content-Type: application/json\nVary:\n- Accept\n- Accept-Encoding\n", string(b))
+}
+
+func TestImposterHandler_MultiValueHeaders(t *testing.T) {
+	headers := map[string]HeaderValues{
+		"Location": {"/gophers/{{ .PathParams.id }}"},
+		"Link": {
+			`</gophers/{{ .PathParams.id }}/friends>; rel="friends"`,
+			`</gophers/{{ .PathParams.id }}/burrows>; rel="burrows"`,
+		},
+	}
+	imposter := Imposter{
+		Request:  Request{Method: http.MethodPost, Endpoint: "/gophers/{id:int}"},
+		Response: Responses{{Status: http.StatusCreated, Headers: &headers, Template: true}},
+	}
+	require.NoError(t, imposter.Response[0].parseTemplates())
+
+	router := mux.NewRouter()
+	router.HandleFunc(expandEndpoint(imposter.Request.Endpoint), ImposterHandler(imposter))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/gophers/42", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/gophers/42", rec.Header().Get("Location"))
+	assert.Equal(t, []string{`</gophers/42/friends>; rel="friends"`, `</gophers/42/burrows>; rel="burrows"`}, rec.Header().Values("Link"))
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/gorilla/mux"
+)
+
+var errInvalidTemplate = errors.New("invalid template")
+
+// responseTemplateData is the data of the request available on the templates of the responses
+type responseTemplateData struct {
+	PathParams  map[string]string
+	QueryParams url.Values
+	Headers     http.Header
+	Cookies     map[string]string
+}
+
+func newResponseTemplateData(r *http.Request) responseTemplateData {
+	cookies := make(map[string]string)
+	for _, c := range r.Cookies() {
+		if _, ok := cookies[c.Name]; !ok {
+			cookies[c.Name] = c.Value
+		}
+	}
+
+	return responseTemplateData{
+		PathParams:  mux.Vars(r),
+		QueryParams: r.URL.Query(),
+		Headers:     r.Header,
+		Cookies:     cookies,
+	}
+}
+
+// responseTemplates are the templates of the body, the headers, the body of the variants and the reason
+// and detail of the SOAP fault of a response, parsed when the response is unmarshalled. The texts without
+// actions don't have a template
+type responseTemplates struct {
+	body       *template.Template
+	headers    map[string][]*template.Template
+	variants   []*template.Template
+	soapReason *template.Template
+	soapDetail *template.Template
+}
+
+// parseTemplates parses the templates of the response, the body of a SOAP fault is its envelope,
+// so the templates are its reason and detail
+func (r *Response) parseTemplates() error {
+	t := &responseTemplates{headers: make(map[string][]*template.Template)}
+
+	var err error
+	if r.SOAPFault != nil {
+		if t.soapReason, err = parseTemplate("reason", r.SOAPFault.Reason); err != nil {
+			return err
+		}
+		if t.soapDetail, err = parseTemplate("detail", r.SOAPFault.Detail); err != nil {
+			return err
+		}
+	} else if t.body, err = parseTemplate("body", r.Body); err != nil {
+		return err
+	}
+
+	if r.Headers != nil {
+		for key, values := range *r.Headers {
+			tmpls := make([]*template.Template, len(values))
+			for i, v := range values {
+				if tmpls[i], err = parseTemplate(key, v); err != nil {
+					return err
+				}
+			}
+			t.headers[http.CanonicalHeaderKey(key)] = tmpls
+		}
+	}
+
+	t.variants = make([]*template.Template, len(r.Variants))
+	for i, v := range r.Variants {
+		if t.variants[i], err = parseTemplate(v.ContentType, v.Body); err != nil {
+			return err
+		}
+	}
+
+	r.templates = t
+	return nil
+}
+
+// bodyTemplate returns the template of the body, or nil when the response isn't a template
+func (t *responseTemplates) bodyTemplate() *template.Template {
+	if t == nil {
+		return nil
+	}
+	return t.body
+}
+
+// soapFaultTemplates returns the templates of the reason and the detail of the SOAP fault,
+// or nil when the response isn't a template
+func (t *responseTemplates) soapFaultTemplates() (reason, detail *template.Template) {
+	if t == nil {
+		return nil, nil
+	}
+	return t.soapReason, t.soapDetail
+}
+
+// headerTemplate returns the template of the value of the header, or nil when the response isn't a template
+func (t *responseTemplates) headerTemplate(key string, i int) *template.Template {
+	key = http.CanonicalHeaderKey(key)
+	if t == nil || i >= len(t.headers[key]) {
+		return nil
+	}
+	return t.headers[key][i]
+}
+
+// parseTemplate parses the text as a Go template, the text without actions doesn't need one
+func parseTemplate(name, text string) (*template.Template, error) {
+	if !strings.Contains(text, "{{") {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes the template with the data of the request, without template the text is returned as is
+func renderTemplate(tmpl *template.Template, text string, data responseTemplateData) (string, error) {
+	if tmpl == nil {
+		return text, nil
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return text, err
+	}
+	return b.String(), nil
+}
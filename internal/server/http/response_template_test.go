@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestResponse_UnmarshalTemplates(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"template":                    {input: `{"template": true, "body": "{{ .PathParams.id }}", "headers": {"Location": "/gophers/{{ .PathParams.id }}"}}`},
+		"invalid text of no template": {input: `{"body": "{{ .PathParams.id "}`},
+		"invalid body":                {input: `{"template": true, "body": "{{ .PathParams.id "}`, err: errInvalidTemplate},
+		"invalid header":              {input: `{"template": true, "headers": {"Location": ["/gophers", "{{ end }}"]}}`, err: errInvalidTemplate},
+		"invalid variant":             {input: `{"template": true, "variants": [{"contentType": "text/plain", "body": "{{ if }}"}]}`, err: errInvalidTemplate},
+		"invalid soap fault":          {input: `{"template": true, "soapFault": {"code": "Client", "reason": "{{ .Headers.Get }"}}`, err: errInvalidTemplate},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var res Response
+			err := json.Unmarshal([]byte(tc.input), &res)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var res Response
+	err := yaml.Unmarshal([]byte("template: true\nbody: \"{{ .Cookies.session \"\n"), &res)
+	assert.True(t, errors.Is(err, errInvalidTemplate), "unexpected error %v", err)
+}
+
+func TestImposterHandler_TemplateOptIn(t *testing.T) {
+	testCases := map[string]struct {
+		response   string
+		accept     string
+		wantHeader string
+		wantBody   string
+	}{
+		"not a template": {
+			response:   `{"status": 200, "headers": {"x-gopher": "{{ .PathParams.id }}"}, "body": "{{ .PathParams.id }}"}`,
+			wantHeader: "{{ .PathParams.id }}",
+			wantBody:   "{{ .PathParams.id }}",
+		},
+		"template": {
+			response:   `{"status": 200, "template": true, "headers": {"x-gopher": "{{ .PathParams.id }}"}, "body": "{{ .PathParams.id }}"}`,
+			wantHeader: "42",
+			wantBody:   "42",
+		},
+		"template of the variant": {
+			response: `{"status": 200, "template": true, "variants": [
+				{"contentType": "application/json", "body": "{\"id\": {{ .PathParams.id }}}"},
+				{"contentType": "text/csv", "body": "id\n{{ .PathParams.id }}"}
+			]}`,
+			accept:   "text/csv",
+			wantBody: "id\n42",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var res Response
+			require.NoError(t, json.Unmarshal([]byte(tc.response), &res))
+			imposter := Imposter{
+				Request:  Request{Method: http.MethodGet, Endpoint: "/gophers/{id}"},
+				Response: Responses{res},
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc(expandEndpoint(imposter.Request.Endpoint), ImposterHandler(imposter))
+
+			req := httptest.NewRequest(http.MethodGet, "/gophers/42", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.wantHeader, rec.Header().Get("X-Gopher"))
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestFileCache_ParseBodyTemplates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "valid.json"), []byte(`{"id": {{ .PathParams.id }}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"id": {{ .PathParams.id }`), 0o644))
+
+	valid, invalid := "valid.json", "invalid.json"
+	cache := NewFileCache()
+	newImposter := func(bodyFile string, template bool) Imposter {
+		return Imposter{BasePath: dir, Response: Responses{{Status: http.StatusOK, BodyFile: &bodyFile, Template: template}}}
+	}
+
+	assert.NoError(t, cache.parseBodyTemplates(newImposter(valid, true)))
+	assert.NoError(t, cache.parseBodyTemplates(newImposter(invalid, false)), "the body files of no templates aren't parsed")
+
+	err := cache.parseBodyTemplates(newImposter(invalid, true))
+	assert.True(t, errors.Is(err, errInvalidTemplate), "unexpected error %v", err)
+}
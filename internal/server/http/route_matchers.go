@@ -2,13 +2,15 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/xeipuuv/gojsonschema"
@@ -18,10 +20,20 @@ import (
 func MatcherBySchema(imposter Imposter) mux.MatcherFunc {
 	return func(req *http.Request, rm *mux.RouteMatch) bool {
 		err := validateSchema(imposter, req)
-
-		// TODO: inject the logger
+		var validationErr *schemaValidationError
+		if imposter.Request.SchemaErrorStatus != 0 && errors.As(err, &validationErr) {
+			// the imposter handler responds with the validation errors
+			recordSchemaErrors(req, func(m *matchedSchemaErrors) { m.schema = validationErr })
+			return true
+		}
+		recordSchemaErrors(req, func(m *matchedSchemaErrors) { m.schema = nil })
 		if err != nil {
-			log.Println(err)
+			loggerFromContext(req.Context()).Debug("request does not match the schema",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"schema_file", *imposter.Request.SchemaFile,
+				"error", err)
 			return false
 		}
 		return true
@@ -33,51 +45,178 @@ func validateSchema(imposter Imposter, req *http.Request) error {
 		return nil
 	}
 
-	var requestBodyBytes []byte
-
-	defer func() {
-		req.Body.Close()
-		req.Body = io.NopCloser(bytes.NewBuffer(requestBodyBytes))
-	}()
+	requestBodyBytes, err := readBody(req)
+	if err != nil {
+		return fmt.Errorf("%w: impossible read the request body", err)
+	}
 
 	schemaFile := imposter.CalculateFilePath(*imposter.Request.SchemaFile)
-	if _, err := os.Stat(schemaFile); os.IsNotExist(err) {
+	schema, err := imposter.files.schema(schemaFile)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("%w: the schema file %s not found", err, schemaFile)
 	}
+	if err != nil {
+		return fmt.Errorf("%w: error compiling the json schema", err)
+	}
 
-	requestBodyBytes, err := io.ReadAll(req.Body)
+	if len(requestBodyBytes) == 0 {
+		return &schemaValidationError{errors: []schemaError{{Field: "(root)", Type: "empty_body", Description: "unexpected empty body request"}}}
+	}
+
+	res, err := schema.Validate(gojsonschema.NewBytesLoader(requestBodyBytes))
 	if err != nil {
-		return fmt.Errorf("%w: impossible read the request body", err)
+		return &schemaValidationError{errors: []schemaError{{Field: "(root)", Type: "invalid_json", Description: err.Error()}}}
+	}
+
+	if !res.Valid() {
+		validationErr := &schemaValidationError{}
+		for _, desc := range res.Errors() {
+			validationErr.errors = append(validationErr.errors, schemaError{Field: desc.Field(), Type: desc.Type(), Description: desc.Description()})
+		}
+		// the errors of the properties are reported in random order
+		sort.SliceStable(validationErr.errors, func(i, j int) bool {
+			return validationErr.errors[i].Field < validationErr.errors[j].Field
+		})
+		return validationErr
 	}
 
-	contentBody := string(requestBodyBytes)
-	if contentBody == "" {
-		return fmt.Errorf("unexpected empty body request")
+	return nil
+}
+
+// readBody reads the body of the request, leaving it to be read again
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// MatcherByXSD check if the XML body of the request, or the content of its SOAP envelope, is valid against the XSD file
+func MatcherByXSD(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		err := validateXSD(imposter, req)
+		var validationErr *schemaValidationError
+		if imposter.Request.SchemaErrorStatus != 0 && errors.As(err, &validationErr) {
+			// the imposter handler responds with the validation errors
+			recordSchemaErrors(req, func(m *matchedSchemaErrors) { m.xsd = validationErr })
+			return true
+		}
+		recordSchemaErrors(req, func(m *matchedSchemaErrors) { m.xsd = nil })
+		if err != nil {
+			loggerFromContext(req.Context()).Debug("request does not match the xsd",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"xsd_file", *imposter.Request.XSDFile,
+				"error", err)
+			return false
+		}
+		return true
+	}
+}
+
+func validateXSD(imposter Imposter, req *http.Request) error {
+	if imposter.Request.XSDFile == nil {
+		return nil
 	}
 
-	schemaFilePath, err := filepath.Abs(schemaFile)
+	body, err := readBody(req)
 	if err != nil {
-		return fmt.Errorf("%w: impossible find the schema", err)
+		return fmt.Errorf("%w: impossible read the request body", err)
 	}
 
-	schemaBytes, err := os.ReadFile(schemaFilePath)
+	xsdFile := imposter.CalculateFilePath(*imposter.Request.XSDFile)
+	schema, err := imposter.files.xsd(xsdFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: the xsd file %s not found", err, xsdFile)
+	}
 	if err != nil {
-		return fmt.Errorf("%w: impossible read the schema", err)
+		return fmt.Errorf("%w: error compiling the xsd", err)
 	}
 
-	schema := gojsonschema.NewStringLoader(string(schemaBytes))
-	document := gojsonschema.NewStringLoader(string(requestBodyBytes))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &schemaValidationError{errors: []schemaError{{Field: "/", Type: "empty_body", Description: "unexpected empty body request"}}}
+	}
 
-	res, err := gojsonschema.Validate(schema, document)
+	doc, err := parseXML(body)
 	if err != nil {
-		return fmt.Errorf("%w: error validating the json schema", err)
+		return &schemaValidationError{errors: []schemaError{{Field: "/", Type: "invalid_xml", Description: err.Error()}}}
 	}
 
-	if !res.Valid() {
-		for _, desc := range res.Errors() {
-			return errors.New(desc.String())
-		}
+	if errs := schema.validateXML(doc); len(errs) > 0 {
+		return &schemaValidationError{errors: errs}
 	}
-
 	return nil
 }
+
+// schemaError is an error found validating the body of a request against its schema
+type schemaError struct {
+	Field       string `json:"field"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// schemaValidationError is returned when the body of a request doesn't match its schema, with all the errors found
+type schemaValidationError struct {
+	errors []schemaError
+}
+
+func (e *schemaValidationError) Error() string {
+	descs := make([]string, 0, len(e.errors))
+	for _, err := range e.errors {
+		descs = append(descs, err.Field+": "+err.Description)
+	}
+	return strings.Join(descs, "; ")
+}
+
+type schemaErrorsCtxKey struct{}
+
+// matchedSchemaErrors are the validation errors found by the schema matchers of the last imposter tried,
+// so the handler of the imposter that matches responds with them without validating the body again
+type matchedSchemaErrors struct {
+	schema *schemaValidationError
+	xsd    *schemaValidationError
+}
+
+// withMatchedSchemaErrors returns the context with a place for the errors found by the schema matchers
+func withMatchedSchemaErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, schemaErrorsCtxKey{}, &matchedSchemaErrors{})
+}
+
+func recordSchemaErrors(r *http.Request, record func(m *matchedSchemaErrors)) {
+	if m, ok := r.Context().Value(schemaErrorsCtxKey{}).(*matchedSchemaErrors); ok {
+		record(m)
+	}
+}
+
+// writeSchemaErrors responds with the validation errors found by the schema matchers of the imposter,
+// when it has a schemaErrorStatus
+func writeSchemaErrors(w http.ResponseWriter, r *http.Request, i Imposter) bool {
+	if i.Request.SchemaErrorStatus == 0 {
+		return false
+	}
+
+	m, ok := r.Context().Value(schemaErrorsCtxKey{}).(*matchedSchemaErrors)
+	if !ok {
+		return false
+	}
+
+	validationErr := m.schema
+	if validationErr == nil {
+		validationErr = m.xsd
+	}
+	if validationErr == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(i.Request.SchemaErrorStatus)
+	json.NewEncoder(w).Encode(struct {
+		Errors []schemaError `json:"errors"`
+	}{validationErr.errors})
+	return true
+}
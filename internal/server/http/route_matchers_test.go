@@ -2,13 +2,20 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestMatcherBySchema(t *testing.T) {
@@ -72,6 +79,137 @@ func TestMatcherBySchema(t *testing.T) {
 	}
 }
 
+func TestRequest_UnmarshalSchemaErrorStatus(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   bool
+	}{
+		"without status":   {input: `{"method": "POST", "endpoint": "/gophers"}`},
+		"client error":     {input: `{"method": "POST", "endpoint": "/gophers", "schemaErrorStatus": 422}`},
+		"server error":     {input: `{"method": "POST", "endpoint": "/gophers", "schemaErrorStatus": 500}`, err: true},
+		"not a status":     {input: `{"method": "POST", "endpoint": "/gophers", "schemaErrorStatus": 42}`, err: true},
+		"too big a status": {input: `{"method": "POST", "endpoint": "/gophers", "schemaErrorStatus": 4220}`, err: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var r Request
+			err := json.Unmarshal([]byte(tc.input), &r)
+			if tc.err {
+				assert.True(t, errors.Is(err, errInvalidRequest), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var r Request
+	err := yaml.Unmarshal([]byte("method: POST\nendpoint: /gophers\nschemaErrorStatus: 200\n"), &r)
+	assert.True(t, errors.Is(err, errInvalidRequest), "unexpected error %v", err)
+}
+
+func TestImposterHandler_SchemaErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "definitions.json"), []byte(`{
+		"definitions": {"color": {"type": "string", "enum": ["purple", "blue"]}}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.json"), []byte(`{
+		"type": "object",
+		"required": ["name", "color"],
+		"properties": {
+			"name": {"type": "string"},
+			"color": {"$ref": "definitions.json#/definitions/color"},
+			"age": {"type": "integer"}
+		}
+	}`), 0o644))
+
+	schemaFile, missingFile := "gopher.json", "missing.json"
+	imposter := Imposter{
+		BasePath: dir,
+		Request:  Request{Method: http.MethodPost, Endpoint: "/gophers", SchemaFile: &schemaFile, SchemaErrorStatus: http.StatusUnprocessableEntity},
+		Response: Responses{{Status: http.StatusCreated}},
+		files:    NewFileCache(),
+	}
+
+	testCases := map[string]struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"valid body": {
+			body:       `{"name": "Zebediah", "color": "purple"}`,
+			wantStatus: http.StatusCreated,
+		},
+		"invalid body": {
+			body:       `{"color": "green", "age": "old"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"errors": [
+				{"field": "(root)", "type": "required", "description": "name is required"},
+				{"field": "age", "type": "invalid_type", "description": "Invalid type. Expected: integer, given: string"},
+				{"field": "color", "type": "enum", "description": "color must be one of the following: \"purple\", \"blue\""}
+			]}`,
+		},
+		"empty body": {
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"errors": [{"field": "(root)", "type": "empty_body", "description": "unexpected empty body request"}]}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(tc.body))
+			req = req.WithContext(withMatchedSchemaErrors(req.Context()))
+			require.True(t, MatcherBySchema(imposter)(req, nil))
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("errors of an imposter that doesn't match", func(t *testing.T) {
+		withHeader := imposter
+		withHeader.Request.Headers = &map[string]string{"X-Gopher": "Zebediah"}
+		fallback := Imposter{
+			Request:  Request{Method: http.MethodPost, Endpoint: "/gophers"},
+			Response: Responses{{Status: http.StatusOK, Body: "fallback"}},
+		}
+
+		router := mux.NewRouter()
+		for _, imposter := range []Imposter{withHeader, fallback} {
+			r := router.HandleFunc(imposter.Request.Endpoint, ImposterHandler(imposter)).
+				Methods(imposter.Request.Method).
+				MatcherFunc(MatcherBySchema(imposter)).
+				MatcherFunc(MatcherByXSD(imposter))
+			if imposter.Request.Headers != nil {
+				for k, v := range *imposter.Request.Headers {
+					r.HeadersRegexp(k, v)
+				}
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"color": "green"}`))
+		req = req.WithContext(withMatchedSchemaErrors(req.Context()))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "fallback", rec.Body.String())
+	})
+
+	t.Run("missing schema file", func(t *testing.T) {
+		imposter := imposter
+		imposter.Request.SchemaFile = &missingFile
+		req := httptest.NewRequest(http.MethodPost, "/gophers", strings.NewReader(`{"name": "Zebediah"}`))
+		assert.False(t, MatcherBySchema(imposter)(req, nil))
+	})
+}
+
 type errReader int
 
 func (errReader) Read(p []byte) (n int, err error) {
@@ -5,12 +5,17 @@ import (
 	"crypto/tls"
 	_ "embed"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
 
 	killgrave "github.com/friendsofgo/killgrave/internal"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:embed cert/server.key
@@ -30,21 +35,53 @@ type ServerOpt func(s *Server)
 
 // Server definition of mock server
 type Server struct {
-	router     *mux.Router
-	httpServer *http.Server
-	proxy      *Proxy
-	secure     bool
-	imposterFs ImposterFs
+	router      *mux.Router
+	httpServer  *http.Server
+	proxy       *Proxy
+	secure      bool
+	imposterFs  []ImposterFs
+	logger      *slog.Logger
+	accessLog   bool
+	diagnostics bool
+	imposters   []Imposter
+	name        string
+	metrics     *Metrics
+	tracer      trace.Tracer
+	oauth2      *OAuth2Provider
+	files       *FileCache
 }
 
 // NewServer initialize the mock server
-func NewServer(r *mux.Router, httpServer *http.Server, proxyServer *Proxy, secure bool, fs ImposterFs) Server {
-	return Server{
+func NewServer(r *mux.Router, httpServer *http.Server, proxyServer *Proxy, secure bool, fs ImposterFs, opts ...ServerOpt) Server {
+	s := Server{
 		router:     r,
 		httpServer: httpServer,
 		proxy:      proxyServer,
 		secure:     secure,
-		imposterFs: fs,
+		imposterFs: []ImposterFs{fs},
+		logger:     slog.Default(),
+		name:       killgrave.DefaultServerName,
+		files:      NewFileCache(),
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// WithName sets the name of the server, used to distinguish the metrics of several servers
+func WithName(name string) ServerOpt {
+	return func(s *Server) {
+		s.name = name
+	}
+}
+
+// WithOverrides adds more sources of imposters to the server, the later
+// sources override the earlier ones
+func WithOverrides(fs ...ImposterFs) ServerOpt {
+	return func(s *Server) {
+		s.imposterFs = append(s.imposterFs, fs...)
 	}
 }
 
@@ -80,26 +117,27 @@ func PrepareAccessControl(config killgrave.ConfigCORS) (h []handlers.CORSOption)
 // Build read all the files on the impostersPath and add different
 // handlers for each imposter
 func (s *Server) Build() error {
+	if s.httpServer.Handler != nil {
+		s.httpServer.Handler = s.instrument(s.httpServer.Handler)
+	}
+
+	if s.oauth2 != nil {
+		s.oauth2.register(s.router)
+	}
+
 	if s.proxy.mode == killgrave.ProxyAll {
 		// not necessary load the imposters if you will use the tool as a proxy
 		s.router.PathPrefix("/").HandlerFunc(s.proxy.Handler())
 		return nil
 	}
 
-	var impostersCh = make(chan []Imposter)
-
-	go func() {
-		s.imposterFs.FindImposters(impostersCh)
-	}()
-loop:
-	for {
-		imposters, ok := <-impostersCh
-		if !ok {
-			break loop
-		}
-
-		s.addImposterHandler(imposters)
-		log.Printf("imposter %s loaded\n", imposters[0].Path)
+	for _, file := range s.loadImposterFiles() {
+		s.addImposterHandler(file.imposters)
+		s.logger.Info("imposter loaded", "imposter", file.path, "source", s.imposterFs[file.sourceIdx].Source())
+	}
+	if s.diagnostics {
+		s.router.NotFoundHandler = s.diagnosticsHandler(http.StatusNotFound)
+		s.router.MethodNotAllowedHandler = s.diagnosticsHandler(http.StatusMethodNotAllowed)
 	}
 	if s.proxy.mode == killgrave.ProxyMissing {
 		s.router.NotFoundHandler = s.proxy.Handler()
@@ -107,18 +145,124 @@ loop:
 	return nil
 }
 
+// imposterFile represents the imposters loaded from a single file of a source
+type imposterFile struct {
+	sourceIdx int
+	path      string
+	imposters []Imposter
+}
+
+// loadImposterFiles reads the imposters of all the sources. A file with the same path
+// on a later source replaces the one of the earlier sources, and the files are returned
+// from the highest priority source to the lowest, so their imposters are matched first.
+// The imposters shadowed by the ones of a later source are reported.
+func (s *Server) loadImposterFiles() []imposterFile {
+	var files []imposterFile
+	index := make(map[string]int)
+
+	for sourceIdx, ifs := range s.imposterFs {
+		var impostersCh = make(chan []Imposter)
+
+		go func() {
+			ifs.FindImposters(impostersCh)
+		}()
+
+		for imposters := range impostersCh {
+			if len(imposters) == 0 {
+				continue
+			}
+
+			file := imposterFile{sourceIdx: sourceIdx, path: imposters[0].Path, imposters: imposters}
+			if idx, ok := index[file.path]; ok {
+				s.logger.Info("imposter overridden",
+					"imposter", file.path,
+					"source", s.imposterFs[files[idx].sourceIdx].Source(),
+					"override", ifs.Source())
+				files[idx] = file
+				continue
+			}
+
+			index[file.path] = len(files)
+			files = append(files, file)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].sourceIdx > files[j].sourceIdx
+	})
+
+	s.reportConflicts(files)
+	return files
+}
+
+func (s *Server) reportConflicts(files []imposterFile) {
+	type definition struct {
+		sourceIdx int
+		path      string
+	}
+
+	seen := make(map[string]definition)
+	for _, file := range files {
+		for _, imposter := range file.imposters {
+			key := requestKey(imposter.Request)
+			prev, ok := seen[key]
+			if !ok {
+				seen[key] = definition{sourceIdx: file.sourceIdx, path: file.path}
+				continue
+			}
+
+			if prev.sourceIdx != file.sourceIdx {
+				s.logger.Warn("imposter shadowed by another source",
+					"method", imposter.Request.Method,
+					"endpoint", imposter.Request.Endpoint,
+					"imposter", file.path,
+					"source", s.imposterFs[file.sourceIdx].Source(),
+					"shadowed_by", prev.path,
+					"shadowed_by_source", s.imposterFs[prev.sourceIdx].Source())
+			}
+		}
+	}
+}
+
+// requestKey identifies the requests that are matched by an imposter
+func requestKey(r Request) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(r.Method) + " " + r.Endpoint)
+
+	writeMap := func(m *map[string]string) {
+		if m == nil {
+			return
+		}
+		for _, k := range sortedKeys(*m) {
+			b.WriteString("|" + k + "=" + (*m)[k])
+		}
+	}
+
+	b.WriteString(" params")
+	writeMap(r.Params)
+	b.WriteString(" headers")
+	writeMap(r.Headers)
+	if r.SchemaFile != nil {
+		b.WriteString(" schema=" + *r.SchemaFile)
+	}
+	if r.XSDFile != nil {
+		b.WriteString(" xsd=" + *r.XSDFile)
+	}
+	if r.SOAP != nil {
+		b.WriteString(" soap " + r.SOAP.String())
+	}
+	return b.String()
+}
+
 // Run launch a previous configured http server if any error happens while the starting process
 // application will be crashed
 func (s *Server) Run() {
 	go func() {
-		var tlsString string
-		if s.secure {
-			tlsString = "(TLS mode)"
-		}
-		log.Printf("The fake server is on tap now: %s%s\n", s.httpServer.Addr, tlsString)
+		s.logger.Info("the fake server is on tap now", "addr", s.httpServer.Addr, "tls", s.secure)
 		err := s.run(s.secure)
 		if !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal(err)
+			s.logger.Error("the fake server has crashed", "addr", s.httpServer.Addr, "error", err)
+			os.Exit(1)
 		}
 	}()
 }
@@ -130,7 +274,7 @@ func (s *Server) run(secure bool) error {
 
 	cert, err := tls.X509KeyPair(serverCert, serverKey)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	s.httpServer.TLSConfig = &tls.Config{
@@ -140,21 +284,57 @@ func (s *Server) run(secure bool) error {
 	return s.httpServer.ListenAndServeTLS("", "")
 }
 
-// Shutdown shutdowns the current http server
+// Shutdown shutdowns the current http server, removing the temporary files of its imposters,
+// and their entries of the cache, even when the server fails to stop
 func (s *Server) Shutdown() error {
-	log.Println("stopping server...")
-	if err := s.httpServer.Shutdown(context.TODO()); err != nil {
-		log.Fatalf("Server Shutdown Failed:%+v", err)
+	s.logger.Info("stopping server...", "addr", s.httpServer.Addr)
+	err := s.httpServer.Shutdown(context.TODO())
+
+	for _, ifs := range s.imposterFs {
+		if err := ifs.Close(); err != nil {
+			s.logger.Warn("error cleaning the imposters", "source", ifs.Source(), "error", err)
+		}
+		if ifs.tmpDir != "" {
+			s.files.Invalidate(ifs.tmpDir)
+		}
 	}
 
+	if err != nil {
+		return fmt.Errorf("%w: server shutdown failed", err)
+	}
 	return nil
 }
 
 func (s *Server) addImposterHandler(imposters []Imposter) {
 	for _, imposter := range imposters {
-		r := s.router.HandleFunc(imposter.Request.Endpoint, ImposterHandler(imposter)).
+		proxies, err := newImposterProxies(imposter)
+		if err == nil {
+			err = imposter.Request.Auth.load(imposter)
+		}
+		if err != nil {
+			s.logger.Error("the imposter is skipped", "imposter", imposter.Path, "method", imposter.Request.Method, "endpoint", imposter.Request.Endpoint, "error", err)
+			continue
+		}
+
+		imposter.files = s.files
+		for _, err := range s.files.preload(imposter) {
+			s.logger.Warn("error loading the imposter files", "imposter", imposter.Path, "error", err)
+		}
+		if err := s.files.parseBodyTemplates(imposter); err != nil {
+			s.logger.Error("the imposter is skipped", "imposter", imposter.Path, "method", imposter.Request.Method, "endpoint", imposter.Request.Endpoint, "error", err)
+			continue
+		}
+		s.imposters = append(s.imposters, imposter)
+		r := s.router.HandleFunc(expandEndpoint(imposter.Request.Endpoint), imposterHandler(imposter, proxies)).
 			Methods(imposter.Request.Method).
-			MatcherFunc(MatcherBySchema(imposter))
+			MatcherFunc(MatcherBySchema(imposter)).
+			MatcherFunc(MatcherByXSD(imposter)).
+			MatcherFunc(MatcherBySOAP(imposter)).
+			MatcherFunc(MatcherByQuery(imposter)).
+			MatcherFunc(MatcherByAuth(imposter)).
+			MatcherFunc(MatcherByForm(imposter)).
+			MatcherFunc(MatcherByCookies(imposter)).
+			MatcherFunc(MatcherByPredicates(imposter))
 
 		if imposter.Request.Headers != nil {
 			for k, v := range *imposter.Request.Headers {
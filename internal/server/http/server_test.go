@@ -129,6 +129,36 @@ func TestBuildProxyMode(t *testing.T) {
 	}
 }
 
+func TestServer_Build_MultipleSources(t *testing.T) {
+	base, err := NewImposterFS("test/testdata/imposters")
+	require.NoError(t, err)
+
+	override, err := NewImposterFS("test/testdata/imposters_override")
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	srv := NewServer(router, &http.Server{Handler: router}, &Proxy{}, false, base, WithOverrides(override))
+	require.NoError(t, srv.Build())
+
+	testCases := map[string]struct {
+		url  string
+		body string
+	}{
+		"file overridden by a later source":   {url: "/testRequest", body: "Overridden"},
+		"imposter shadowed by a later source": {url: "/yamlTestRequest", body: "Yaml Overridden"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tc.url, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.body, w.Body.String())
+		})
+	}
+}
+
 func TestBuildSecureMode(t *testing.T) {
 	proxyServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		io.WriteString(w, "Proxied")
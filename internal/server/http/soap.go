@@ -0,0 +1,305 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+
+	// SOAP11 is the version 1.1 of SOAP, the default one
+	SOAP11 = "1.1"
+	// SOAP12 is the version 1.2 of SOAP
+	SOAP12 = "1.2"
+)
+
+var (
+	errInvalidSOAP = errors.New("invalid soap")
+
+	// soapFaultCodes are the codes of the faults on each version, the codes of one version are translated to the other one
+	soapFaultCodes = map[string]map[string]string{
+		SOAP11: {"Client": "Client", "Sender": "Client", "Server": "Server", "Receiver": "Server", "VersionMismatch": "VersionMismatch", "MustUnderstand": "MustUnderstand"},
+		SOAP12: {"Client": "Sender", "Sender": "Sender", "Server": "Receiver", "Receiver": "Receiver", "VersionMismatch": "VersionMismatch", "MustUnderstand": "MustUnderstand", "DataEncodingUnknown": "DataEncodingUnknown"},
+	}
+)
+
+// RequestSOAP matches the SOAP requests by their action and by the operation, the first element of the
+// envelope body. The operation is either a local name, like GetGopher, or a prefixed name, like
+// gop:GetGopher, whose prefix is declared on the namespaces of the request
+type RequestSOAP struct {
+	Action    string `json:"action,omitempty" yaml:"action,omitempty"`
+	Operation string `json:"operation,omitempty" yaml:"operation,omitempty"`
+}
+
+// String describes the operation, as it's shown by the diagnostics
+func (s RequestSOAP) String() string {
+	var desc []string
+	if s.Action != "" {
+		desc = append(desc, "action="+s.Action)
+	}
+	if s.Operation != "" {
+		desc = append(desc, "operation="+s.Operation)
+	}
+	return strings.Join(desc, " ")
+}
+
+// MatcherBySOAP check if the request is a SOAP envelope with the action and operation of the imposter
+func MatcherBySOAP(imposter Imposter) mux.MatcherFunc {
+	return func(req *http.Request, rm *mux.RouteMatch) bool {
+		if imposter.Request.SOAP == nil {
+			return true
+		}
+
+		if err := validateSOAP(imposter, req); err != nil {
+			loggerFromContext(req.Context()).Debug("request does not match the soap operation",
+				"imposter", imposter.Path,
+				"method", imposter.Request.Method,
+				"endpoint", imposter.Request.Endpoint,
+				"error", err)
+			return false
+		}
+		return true
+	}
+}
+
+func validateSOAP(imposter Imposter, req *http.Request) error {
+	soap := imposter.Request.SOAP
+	if soap.Action != "" {
+		if action := soapAction(req); action != soap.Action {
+			return fmt.Errorf("the soap action %q is not %q", action, soap.Action)
+		}
+	}
+
+	if soap.Operation == "" {
+		return nil
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return fmt.Errorf("%w: impossible read the request body", err)
+	}
+
+	doc, err := parseXML(body)
+	if err != nil {
+		return fmt.Errorf("%w: the request body is not xml", err)
+	}
+
+	op := soapOperation(doc)
+	if op == nil {
+		return errors.New("the request body is not a soap envelope with an operation")
+	}
+
+	prefix, local, ok := strings.Cut(soap.Operation, ":")
+	if !ok {
+		prefix, local = "", soap.Operation
+	}
+	if op.local != local {
+		return fmt.Errorf("the soap operation %s is not %s", op.local, soap.Operation)
+	}
+	if prefix != "" && op.space != imposter.Request.Namespaces[prefix] {
+		return fmt.Errorf("the namespace of the soap operation %s is %q", op.local, op.space)
+	}
+	return nil
+}
+
+// soapAction returns the action of the request, from the SOAPAction header of SOAP 1.1
+// or the action parameter of the Content-Type of SOAP 1.2
+func soapAction(req *http.Request) string {
+	if action := req.Header.Get("SOAPAction"); action != "" {
+		return strings.Trim(action, `"`)
+	}
+
+	if _, params, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil {
+		return params["action"]
+	}
+	return ""
+}
+
+// soapEnvelope returns the envelope of the document, if it's a SOAP envelope
+func soapEnvelope(doc *xmlNode) *xmlNode {
+	env := doc.firstElement()
+	if env == nil || env.local != "Envelope" || (env.space != soap11Namespace && env.space != soap12Namespace) {
+		return nil
+	}
+	return env
+}
+
+// soapBody returns the body of the envelope, if the document is a SOAP envelope
+func soapBody(doc *xmlNode) *xmlNode {
+	env := soapEnvelope(doc)
+	if env == nil {
+		return nil
+	}
+
+	for _, el := range env.elements() {
+		if el.local == "Body" && el.space == env.space {
+			return el
+		}
+	}
+	return nil
+}
+
+// soapOperation returns the first element of the body of the envelope
+func soapOperation(doc *xmlNode) *xmlNode {
+	body := soapBody(doc)
+	if body == nil {
+		return nil
+	}
+	return body.firstElement()
+}
+
+// SOAPFault builds the body of a response as a SOAP fault. The Code is one of Client, Server,
+// VersionMismatch or MustUnderstand on SOAP 1.1, and Sender, Receiver, VersionMismatch, MustUnderstand
+// or DataEncodingUnknown on SOAP 1.2, being translated between versions. The Reason is escaped, once it's
+// rendered when the response is a template, and the Detail is raw XML, which must be well-formed
+type SOAPFault struct {
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	Code    string `json:"code" yaml:"code"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Detail  string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// UnmarshalJSON of json.Unmarshaler interface, it validates the fault.
+func (f *SOAPFault) UnmarshalJSON(data []byte) error {
+	type soapFault SOAPFault
+	if err := json.Unmarshal(data, (*soapFault)(f)); err != nil {
+		return err
+	}
+	return f.validate()
+}
+
+// UnmarshalYAML of yaml.Unmarshaler interface, it validates the fault.
+func (f *SOAPFault) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type soapFault SOAPFault
+	if err := unmarshal((*soapFault)(f)); err != nil {
+		return err
+	}
+	return f.validate()
+}
+
+func (f *SOAPFault) validate() error {
+	codes, ok := soapFaultCodes[f.version()]
+	if !ok {
+		return fmt.Errorf("%w: unknown version %q, the options are %s or %s", errInvalidSOAP, f.Version, SOAP11, SOAP12)
+	}
+	if _, ok := codes[f.Code]; !ok {
+		return fmt.Errorf("%w: unknown fault code %q", errInvalidSOAP, f.Code)
+	}
+	return nil
+}
+
+func (f *SOAPFault) version() string {
+	if f.Version == "" {
+		return SOAP11
+	}
+	return f.Version
+}
+
+// contentType returns the media type of the SOAP messages of the version
+func (f *SOAPFault) contentType() string {
+	if f.version() == SOAP12 {
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+// validateDetail checks the detail is well-formed XML, the details with actions of the templates
+// are checked once they're rendered
+func (f *SOAPFault) validateDetail(template bool) error {
+	if f.Detail == "" || (template && strings.Contains(f.Detail, "{{")) {
+		return nil
+	}
+	return f.checkDetail(f.Detail)
+}
+
+// checkDetail parses the detail inside the fault of the envelope, where the soap prefix is declared
+func (f *SOAPFault) checkDetail(detail string) error {
+	ns := soap11Namespace
+	if f.version() == SOAP12 {
+		ns = soap12Namespace
+	}
+	if _, err := parseXML([]byte(`<detail xmlns:soap="` + ns + `">` + detail + `</detail>`)); err != nil {
+		return fmt.Errorf("%w: the detail of the fault is not well-formed xml: %v", errInvalidSOAP, err)
+	}
+	return nil
+}
+
+// envelope returns the envelope with the fault, escaping the reason and writing the detail as it is
+func (f *SOAPFault) envelope(reason, detail string) string {
+	code := soapFaultCodes[f.version()][f.Code]
+
+	var b strings.Builder
+	if f.version() == SOAP12 {
+		b.WriteString(`<soap:Envelope xmlns:soap="` + soap12Namespace + `"><soap:Body><soap:Fault>`)
+		b.WriteString(`<soap:Code><soap:Value>soap:` + code + `</soap:Value></soap:Code>`)
+		b.WriteString(`<soap:Reason><soap:Text xml:lang="en">` + xmlEscape(reason) + `</soap:Text></soap:Reason>`)
+		if detail != "" {
+			b.WriteString(`<soap:Detail>` + detail + `</soap:Detail>`)
+		}
+	} else {
+		b.WriteString(`<soap:Envelope xmlns:soap="` + soap11Namespace + `"><soap:Body><soap:Fault>`)
+		b.WriteString(`<faultcode>soap:` + code + `</faultcode>`)
+		b.WriteString(`<faultstring>` + xmlEscape(reason) + `</faultstring>`)
+		if detail != "" {
+			b.WriteString(`<detail>` + detail + `</detail>`)
+		}
+	}
+	b.WriteString(`</soap:Fault></soap:Body></soap:Envelope>`)
+	return b.String()
+}
+
+// soapFaultResponse replaces the body of the response with its SOAP fault, if it has one, with the
+// Content-Type of its version unless the response sets one. The status of the faults is 500 by default
+func soapFaultResponse(i Imposter, res Response, r *http.Request) Response {
+	if res.SOAPFault == nil {
+		return res
+	}
+
+	reason, detail := res.SOAPFault.Reason, res.SOAPFault.Detail
+	if reasonTmpl, detailTmpl := res.templates.soapFaultTemplates(); reasonTmpl != nil || detailTmpl != nil {
+		logger := loggerFromContext(r.Context())
+		data := newResponseTemplateData(r)
+
+		var err error
+		if reason, err = renderTemplate(reasonTmpl, reason, data); err != nil {
+			logger.Error("error rendering the soap fault reason template", "imposter", i.Path, "error", err)
+		}
+		if detail, err = renderTemplate(detailTmpl, detail, data); err != nil {
+			logger.Error("error rendering the soap fault detail template", "imposter", i.Path, "error", err)
+		}
+		if detailTmpl != nil {
+			if err := res.SOAPFault.checkDetail(detail); err != nil {
+				logger.Error("invalid rendered soap fault detail", "imposter", i.Path, "error", err)
+			}
+		}
+	}
+
+	res.Body, res.BodyFile = res.SOAPFault.envelope(reason, detail), nil
+	if res.Status == 0 {
+		res.Status = http.StatusInternalServerError
+	}
+
+	headers := map[string]HeaderValues{"Content-Type": {res.SOAPFault.contentType()}}
+	if res.Headers != nil {
+		for k, v := range *res.Headers {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	res.Headers = &headers
+	return res
+}
+
+// xmlEscaper escapes the text of the elements, leaving the quotes of the templates as they are
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMatcherBySOAP(t *testing.T) {
+	soap12Envelope := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+  <env:Body><GetGopher xmlns="http://example.com/gophers"><id>1</id></GetGopher></env:Body>
+</env:Envelope>`
+
+	testCases := map[string]struct {
+		soap    RequestSOAP
+		request func() *http.Request
+		want    bool
+	}{
+		"soap 1.1 action": {
+			soap: RequestSOAP{Action: "http://example.com/gophers/GetGopher"},
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+				req.Header.Set("SOAPAction", `"http://example.com/gophers/GetGopher"`)
+				return req
+			},
+			want: true,
+		},
+		"soap 1.2 action": {
+			soap: RequestSOAP{Action: "http://example.com/gophers/GetGopher"},
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(soap12Envelope))
+				req.Header.Set("Content-Type", `application/soap+xml; charset=utf-8; action="http://example.com/gophers/GetGopher"`)
+				return req
+			},
+			want: true,
+		},
+		"other action": {
+			soap: RequestSOAP{Action: "http://example.com/gophers/GetGopher"},
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+				req.Header.Set("SOAPAction", "http://example.com/gophers/DeleteGopher")
+				return req
+			},
+		},
+		"local operation": {
+			soap: RequestSOAP{Operation: "GetGopher"},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(soap12Envelope))
+			},
+			want: true,
+		},
+		"prefixed operation": {
+			soap: RequestSOAP{Operation: "g:GetGopher"},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+			},
+			want: true,
+		},
+		"prefixed operation of other namespace": {
+			soap: RequestSOAP{Operation: "o:GetGopher"},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+			},
+		},
+		"other operation": {
+			soap: RequestSOAP{Operation: "DeleteGopher"},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+			},
+		},
+		"not an envelope": {
+			soap: RequestSOAP{Operation: "GetGopher"},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(`<GetGopher><id>1</id></GetGopher>`))
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			soap := tc.soap
+			imposter := Imposter{Request: Request{
+				Method:     http.MethodPost,
+				Endpoint:   "/soap",
+				SOAP:       &soap,
+				Namespaces: map[string]string{"g": "http://example.com/gophers", "o": "http://example.com/others"},
+			}}
+			assert.Equal(t, tc.want, MatcherBySOAP(imposter)(tc.request(), nil))
+		})
+	}
+}
+
+func TestSOAPFault_Unmarshal(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   error
+	}{
+		"soap 1.1 fault":        {input: `{"code": "Client", "reason": "Gopher not found"}`},
+		"soap 1.2 fault":        {input: `{"version": "1.2", "code": "Sender", "reason": "Gopher not found"}`},
+		"translated code":       {input: `{"version": "1.2", "code": "Server", "reason": "Unavailable"}`},
+		"unknown version":       {input: `{"version": "2.0", "code": "Client", "reason": "Gopher not found"}`, err: errInvalidSOAP},
+		"unknown code":          {input: `{"code": "NotFound", "reason": "Gopher not found"}`, err: errInvalidSOAP},
+		"code of soap 1.2 only": {input: `{"code": "DataEncodingUnknown", "reason": "Unknown encoding"}`, err: errInvalidSOAP},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var f SOAPFault
+			err := json.Unmarshal([]byte(tc.input), &f)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var f SOAPFault
+	err := yaml.Unmarshal([]byte("code: Teapot\nreason: I'm a teapot\n"), &f)
+	assert.True(t, errors.Is(err, errInvalidSOAP))
+}
+
+func TestResponse_UnmarshalSOAPFaultDetail(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   bool
+	}{
+		"well-formed detail":         {input: `{"soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<g:id xmlns:g=\"http://example.com/gophers\">1</g:id>"}}`},
+		"prefix of the envelope":     {input: `{"soapFault": {"version": "1.2", "code": "Client", "reason": "Gopher not found", "detail": "<soap:Text>1</soap:Text>"}}`},
+		"template detail":            {input: `{"template": true, "soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<id>{{ .PathParams.id }}</id>"}}`},
+		"not closed detail":          {input: `{"soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<id>1"}}`, err: true},
+		"undeclared prefix":          {input: `{"soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<g:id>1</g:id>"}}`, err: true},
+		"actions of no template":     {input: `{"soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<id>{{ .PathParams.id }}</i>"}}`, err: true},
+		"template detail not closed": {input: `{"template": true, "soapFault": {"code": "Client", "reason": "Gopher not found", "detail": "<id>1</i>"}}`, err: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var res Response
+			err := json.Unmarshal([]byte(tc.input), &res)
+			if tc.err {
+				assert.True(t, errors.Is(err, errInvalidSOAP), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	var res Response
+	err := yaml.Unmarshal([]byte("soapFault:\n  code: Client\n  reason: Gopher not found\n  detail: <id>1\n"), &res)
+	assert.True(t, errors.Is(err, errInvalidSOAP), "unexpected error %v", err)
+}
+
+func TestImposterHandler_SOAPFault(t *testing.T) {
+	testCases := map[string]struct {
+		response        string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"soap 1.1 fault": {
+			response:        `{"template": true, "soapFault": {"code": "Client", "reason": "Gopher {{ .Headers.Get \"X-Gopher-Id\" }} & friends not found", "detail": "<g:id xmlns:g=\"http://example.com/gophers\">1</g:id>"}}`,
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/xml; charset=utf-8",
+			wantBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+				`<faultcode>soap:Client</faultcode><faultstring>Gopher 1 &amp; friends not found</faultstring>` +
+				`<detail><g:id xmlns:g="http://example.com/gophers">1</g:id></detail>` +
+				`</soap:Fault></soap:Body></soap:Envelope>`,
+		},
+		"soap 1.2 fault": {
+			response:        `{"status": 400, "soapFault": {"version": "1.2", "code": "Client", "reason": "Gopher not found"}}`,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/soap+xml; charset=utf-8",
+			wantBody: `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><soap:Fault>` +
+				`<soap:Code><soap:Value>soap:Sender</soap:Value></soap:Code>` +
+				`<soap:Reason><soap:Text xml:lang="en">Gopher not found</soap:Text></soap:Reason>` +
+				`</soap:Fault></soap:Body></soap:Envelope>`,
+		},
+		"rendered reason is escaped": {
+			response:        `{"template": true, "soapFault": {"code": "Client", "reason": "Gopher {{ .Headers.Get \"X-Gopher-Name\" }} not found", "detail": "<id>{{ .Headers.Get \"X-Gopher-Id\" }}</id>"}}`,
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/xml; charset=utf-8",
+			wantBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+				`<faultcode>soap:Client</faultcode><faultstring>Gopher &lt;Zebediah&gt; not found</faultstring>` +
+				`<detail><id>1</id></detail>` +
+				`</soap:Fault></soap:Body></soap:Envelope>`,
+		},
+		"content type of the response": {
+			response:        `{"headers": {"content-type": "application/xml"}, "soapFault": {"code": "Server", "reason": "Unavailable"}}`,
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/xml",
+			wantBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+				`<faultcode>soap:Server</faultcode><faultstring>Unavailable</faultstring>` +
+				`</soap:Fault></soap:Body></soap:Envelope>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var res Response
+			require.NoError(t, json.Unmarshal([]byte(tc.response), &res))
+			imposter := Imposter{
+				Request:  Request{Method: http.MethodPost, Endpoint: "/soap"},
+				Response: Responses{res},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(getGopherEnvelope))
+			req.Header.Set("X-Gopher-Id", "1")
+			req.Header.Set("X-Gopher-Name", "<Zebediah>")
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/friendsofgo/killgrave/internal/server/http"
+
+// tracePropagator reads and writes the W3C trace context and baggage headers
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// WithTracerProvider enables the tracing of the requests handled by the server
+func WithTracerProvider(tp trace.TracerProvider) ServerOpt {
+	return func(s *Server) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// startRequestSpan starts the span of a request, as a child of the trace context of the incoming
+// request if any, and the span of the imposter matching, that ends when a handler is reached
+func (s *Server) startRequestSpan(r *http.Request, entry *requestEntry) (context.Context, trace.Span) {
+	ctx := tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := s.tracer.Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+			attribute.String("killgrave.server", s.name),
+		))
+
+	_, entry.matchSpan = s.tracer.Start(ctx, "killgrave.match")
+	return ctx, span
+}
+
+// endRequestSpan ends the span of a request already responded
+func endRequestSpan(span trace.Span, entry *requestEntry, status int) {
+	entry.endMatch()
+
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(status),
+		attribute.Bool("killgrave.proxied", entry.proxied),
+	)
+	if entry.imposter != nil {
+		span.SetAttributes(attribute.String("killgrave.imposter", entry.imposter.Path))
+	}
+
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
+// endMatch ends the span of the imposter matching, if it's still running
+func (e *requestEntry) endMatch() {
+	if e.matchSpan == nil {
+		return
+	}
+
+	switch {
+	case e.imposter != nil:
+		e.matchSpan.SetAttributes(attribute.String("killgrave.imposter", e.imposter.Path))
+	case e.proxied:
+		e.matchSpan.SetAttributes(attribute.Bool("killgrave.proxied", true))
+	default:
+		e.matchSpan.SetStatus(codes.Error, "no imposter matches the request")
+	}
+
+	e.matchSpan.End()
+	e.matchSpan = nil
+}
+
+// startSpan starts a child span of the span of the request, if any
+func startSpan(r *http.Request, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(r.Context()).TracerProvider().Tracer(tracerName)
+	return tracer.Start(r.Context(), name, opts...)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	killgrave "github.com/friendsofgo/killgrave/internal"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestServer_Tracing(t *testing.T) {
+	var upstreamTraceparent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		io.WriteString(w, "Proxied")
+	}))
+	defer backend.Close()
+
+	makeServer := func() (http.Handler, *tracetest.SpanRecorder) {
+		proxy, err := NewProxy(backend.URL, killgrave.ProxyMissing)
+		require.NoError(t, err)
+
+		imposterFs, err := NewImposterFS("test/testdata/imposters")
+		require.NoError(t, err)
+
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		router := mux.NewRouter()
+		httpServer := &http.Server{Handler: router}
+		srv := NewServer(router, httpServer, proxy, false, imposterFs, WithTracerProvider(tp))
+		require.NoError(t, srv.Build())
+
+		return httpServer.Handler, recorder
+	}
+
+	t.Run("imposter request", func(t *testing.T) {
+		handler, recorder := makeServer()
+
+		req := httptest.NewRequest("GET", "/testRequest", nil)
+		req.Header.Set("traceparent", incomingTraceparent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 3)
+
+		names := make(map[string]sdktrace.ReadOnlySpan)
+		for _, span := range spans {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+			names[span.Name()] = span
+		}
+
+		require.Contains(t, names, "GET")
+		require.Contains(t, names, "killgrave.match")
+		require.Contains(t, names, "killgrave.response")
+
+		root := names["GET"]
+		assert.Equal(t, "00f067aa0ba902b7", root.Parent().SpanID().String())
+		assert.Equal(t, root.SpanContext().SpanID(), names["killgrave.match"].Parent().SpanID())
+		assert.Equal(t, root.SpanContext().SpanID(), names["killgrave.response"].Parent().SpanID())
+	})
+
+	t.Run("proxied request propagates the context", func(t *testing.T) {
+		handler, recorder := makeServer()
+
+		req := httptest.NewRequest("GET", "/NonExistentURL123", nil)
+		req.Header.Set("traceparent", incomingTraceparent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var proxySpan sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.Name() == "killgrave.proxy" {
+				proxySpan = span
+			}
+		}
+		require.NotNil(t, proxySpan)
+
+		expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + proxySpan.SpanContext().SpanID().String() + "-01"
+		assert.Equal(t, expected, upstreamTraceparent)
+	})
+}
+
+func TestProxyHandler_WithoutTracing(t *testing.T) {
+	var upstreamTraceparent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	proxy, err := NewProxy(backend.URL, killgrave.ProxyAll)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	proxy.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, incomingTraceparent, upstreamTraceparent)
+}
@@ -0,0 +1,322 @@
+package http
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// xmlNode is a node of a parsed XML document: the root, an element or a text
+type xmlNode struct {
+	typ      xpath.NodeType
+	space    string
+	prefix   string
+	local    string
+	text     string
+	attrs    []xmlAttr
+	scope    map[string]string
+	parent   *xmlNode
+	children []*xmlNode
+	pos      int
+}
+
+type xmlAttr struct {
+	space  string
+	prefix string
+	local  string
+	value  string
+}
+
+// parseXML parses the document keeping the prefixes of the names, as the XPath expressions refer to them,
+// and resolving their namespaces
+func parseXML(data []byte) (*xmlNode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := &xmlNode{typ: xpath.RootNode}
+
+	curr := root
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			el := &xmlNode{typ: xpath.ElementNode, prefix: tok.Name.Space, local: tok.Name.Local, scope: make(map[string]string), parent: curr}
+			for _, a := range tok.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					el.scope[a.Name.Local] = a.Value
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					el.scope[""] = a.Value
+				}
+			}
+
+			var ok bool
+			if el.space, ok = el.namespace(tok.Name.Space); !ok {
+				return nil, fmt.Errorf("undeclared namespace prefix %q", tok.Name.Space)
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				attr := xmlAttr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value}
+				if a.Name.Space != "" {
+					if attr.space, ok = el.namespace(a.Name.Space); !ok {
+						return nil, fmt.Errorf("undeclared namespace prefix %q", a.Name.Space)
+					}
+				}
+				el.attrs = append(el.attrs, attr)
+			}
+			curr.appendChild(el)
+			curr = el
+		case xml.EndElement:
+			if curr == root || tok.Name.Space != curr.prefix || tok.Name.Local != curr.local {
+				return nil, fmt.Errorf("unexpected end element </%s>", xmlName(tok.Name.Space, tok.Name.Local))
+			}
+			curr = curr.parent
+		case xml.CharData:
+			if curr == root {
+				if len(bytes.TrimSpace(tok)) > 0 {
+					return nil, errors.New("unexpected text outside of the root element")
+				}
+				continue
+			}
+			if n := len(curr.children); n > 0 && curr.children[n-1].typ == xpath.TextNode {
+				curr.children[n-1].text += string(tok)
+				continue
+			}
+			curr.appendChild(&xmlNode{typ: xpath.TextNode, text: string(tok), parent: curr})
+		}
+	}
+
+	if curr != root {
+		return nil, fmt.Errorf("element <%s> is not closed", xmlName(curr.prefix, curr.local))
+	}
+	if root.firstElement() == nil {
+		return nil, errors.New("the document has no root element")
+	}
+	return root, nil
+}
+
+func (n *xmlNode) appendChild(child *xmlNode) {
+	child.pos = len(n.children)
+	n.children = append(n.children, child)
+}
+
+// elements returns the child elements of the node, without the texts
+func (n *xmlNode) elements() []*xmlNode {
+	var elements []*xmlNode
+	for _, child := range n.children {
+		if child.typ == xpath.ElementNode {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+func (n *xmlNode) firstElement() *xmlNode {
+	for _, child := range n.children {
+		if child.typ == xpath.ElementNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// attr returns the value of the attribute, and whether it's present
+func (n *xmlNode) attr(space, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.space == space && a.local == local {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// namespace resolves the prefix with the namespaces declared on the element or its ancestors
+func (n *xmlNode) namespace(prefix string) (string, bool) {
+	for ; n != nil; n = n.parent {
+		if uri, ok := n.scope[prefix]; ok {
+			return uri, true
+		}
+	}
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace", true
+	}
+	return "", prefix == ""
+}
+
+// value is the text of the node, with the texts of all its descendants
+func (n *xmlNode) value() string {
+	if n.typ == xpath.TextNode {
+		return n.text
+	}
+
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.value())
+	}
+	return b.String()
+}
+
+// path describes the position of the element, like /Envelope/Body/GetGopher/id
+func (n *xmlNode) path() string {
+	if n.parent == nil {
+		return ""
+	}
+	return n.parent.path() + "/" + n.local
+}
+
+func xmlName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+// xmlNavigator implements xpath.NodeNavigator, to evaluate the XPath expressions on a parsed document
+type xmlNavigator struct {
+	root *xmlNode
+	curr *xmlNode
+	attr int
+}
+
+func newXMLNavigator(root *xmlNode) *xmlNavigator {
+	return &xmlNavigator{root: root, curr: root, attr: -1}
+}
+
+func (n *xmlNavigator) NodeType() xpath.NodeType {
+	if n.attr != -1 {
+		return xpath.AttributeNode
+	}
+	return n.curr.typ
+}
+
+func (n *xmlNavigator) LocalName() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].local
+	}
+	return n.curr.local
+}
+
+func (n *xmlNavigator) Prefix() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].prefix
+	}
+	return n.curr.prefix
+}
+
+// NamespaceURL is used by the expressions compiled with namespaces
+func (n *xmlNavigator) NamespaceURL() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].space
+	}
+	return n.curr.space
+}
+
+func (n *xmlNavigator) Value() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].value
+	}
+	return n.curr.value()
+}
+
+func (n *xmlNavigator) Copy() xpath.NodeNavigator {
+	c := *n
+	return &c
+}
+
+func (n *xmlNavigator) MoveToRoot() {
+	n.curr, n.attr = n.root, -1
+}
+
+func (n *xmlNavigator) MoveToParent() bool {
+	if n.attr != -1 {
+		n.attr = -1
+		return true
+	}
+	if n.curr.parent == nil {
+		return false
+	}
+	n.curr = n.curr.parent
+	return true
+}
+
+func (n *xmlNavigator) MoveToNextAttribute() bool {
+	if n.attr+1 >= len(n.curr.attrs) {
+		return false
+	}
+	n.attr++
+	return true
+}
+
+func (n *xmlNavigator) MoveToChild() bool {
+	if n.attr != -1 || len(n.curr.children) == 0 {
+		return false
+	}
+	n.curr = n.curr.children[0]
+	return true
+}
+
+func (n *xmlNavigator) MoveToFirst() bool {
+	if n.attr != -1 || n.curr.parent == nil {
+		return false
+	}
+	n.curr = n.curr.parent.children[0]
+	return true
+}
+
+func (n *xmlNavigator) MoveToNext() bool {
+	if n.attr != -1 || n.curr.parent == nil || n.curr.pos+1 >= len(n.curr.parent.children) {
+		return false
+	}
+	n.curr = n.curr.parent.children[n.curr.pos+1]
+	return true
+}
+
+func (n *xmlNavigator) MoveToPrevious() bool {
+	if n.attr != -1 || n.curr.parent == nil || n.curr.pos == 0 {
+		return false
+	}
+	n.curr = n.curr.parent.children[n.curr.pos-1]
+	return true
+}
+
+func (n *xmlNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	o, ok := other.(*xmlNavigator)
+	if !ok || o.root != n.root {
+		return false
+	}
+	n.curr, n.attr = o.curr, o.attr
+	return true
+}
+
+// xpathValues evaluates the expression on the document, returning the values of the selected nodes,
+// or the result of the expressions that return a string, a number or a boolean
+func xpathValues(doc *xmlNode, expr *xpath.Expr) []string {
+	switch v := expr.Evaluate(newXMLNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		var values []string
+		for v.MoveNext() {
+			values = append(values, v.Current().Value())
+		}
+		return values
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	}
+	return nil
+}
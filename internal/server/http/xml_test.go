@@ -0,0 +1,140 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const getGopherEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:gop="http://example.com/gophers">
+  <soapenv:Header/>
+  <soapenv:Body>
+    <gop:GetGopher version="2">
+      <gop:id>1</gop:id>
+      <gop:tag>blue</gop:tag>
+      <gop:tag>small</gop:tag>
+    </gop:GetGopher>
+  </soapenv:Body>
+</soapenv:Envelope>`
+
+func TestParseXML(t *testing.T) {
+	doc, err := parseXML([]byte(getGopherEnvelope))
+	require.NoError(t, err)
+
+	op := soapOperation(doc)
+	require.NotNil(t, op)
+	assert.Equal(t, "GetGopher", op.local)
+	assert.Equal(t, "http://example.com/gophers", op.space)
+	assert.Equal(t, "/Envelope/Body/GetGopher", op.path())
+
+	version, ok := op.attr("", "version")
+	assert.True(t, ok)
+	assert.Equal(t, "2", version)
+
+	invalid := map[string]string{
+		"not closed":         `<gophers><gopher></gophers>`,
+		"undeclared prefix":  `<gop:gophers/>`,
+		"text outside root":  `<gophers/>text`,
+		"empty":              ``,
+		"json":               `{"name": "Zebediah"}`,
+		"wrong closing name": `<gophers></gopher>`,
+	}
+	for name, input := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseXML([]byte(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestXPathValues(t *testing.T) {
+	doc, err := parseXML([]byte(getGopherEnvelope))
+	require.NoError(t, err)
+
+	namespaces := map[string]string{"s": "http://schemas.xmlsoap.org/soap/envelope/", "g": "http://example.com/gophers"}
+	testCases := map[string]struct {
+		expression string
+		namespaces map[string]string
+		want       []string
+	}{
+		"element text":           {expression: "/s:Envelope/s:Body/g:GetGopher/g:id", namespaces: namespaces, want: []string{"1"}},
+		"repeated elements":      {expression: "//g:tag", namespaces: namespaces, want: []string{"blue", "small"}},
+		"attribute":              {expression: "//g:GetGopher/@version", namespaces: namespaces, want: []string{"2"}},
+		"count":                  {expression: "count(//g:tag)", namespaces: namespaces, want: []string{"2"}},
+		"boolean":                {expression: "//g:id = 1", namespaces: namespaces, want: []string{"true"}},
+		"other namespace":        {expression: "//s:id", namespaces: namespaces},
+		"prefixes of the body":   {expression: "//gop:GetGopher/gop:id", want: []string{"1"}},
+		"other prefix unmatched": {expression: "//g:id"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, err := xpath.CompileWithNS(tc.expression, tc.namespaces)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, xpathValues(doc, expr))
+		})
+	}
+}
+
+func TestRequest_UnmarshalXPathPredicates(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		err   bool
+	}{
+		"declared prefix":          {input: `{"namespaces": {"g": "http://example.com/gophers"}, "predicates": [{"field": "xpath://g:id", "exists": true}]}`},
+		"prefixes of the body":     {input: `{"predicates": [{"field": "xpath://gop:id", "exists": true}]}`},
+		"undeclared prefix":        {input: `{"namespaces": {"g": "http://example.com/gophers"}, "predicates": [{"field": "xpath://x:id", "exists": true}]}`, err: true},
+		"undeclared nested prefix": {input: `{"namespaces": {"g": "http://example.com/gophers"}, "predicates": [{"not": {"or": [{"field": "xpath://g:id", "exists": true}, {"field": "xpath://x:id", "exists": true}]}}]}`, err: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var r Request
+			err := json.Unmarshal([]byte(tc.input), &r)
+			if tc.err {
+				assert.True(t, errors.Is(err, errInvalidPredicate), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMatcherByPredicates_XPath(t *testing.T) {
+	imposter := Imposter{Request: Request{
+		Method:     http.MethodPost,
+		Endpoint:   "/soap",
+		Namespaces: map[string]string{"g": "http://example.com/gophers"},
+	}}
+
+	testCases := map[string]struct {
+		predicates string
+		body       string
+		want       bool
+	}{
+		"equals":            {predicates: `[{"field": "xpath://g:GetGopher/g:id", "equals": "1"}]`, body: getGopherEnvelope, want: true},
+		"not equals":        {predicates: `[{"field": "xpath://g:GetGopher/g:id", "equals": "2"}]`, body: getGopherEnvelope},
+		"any of the values": {predicates: `[{"field": "xpath://g:tag", "equals": "small"}]`, body: getGopherEnvelope, want: true},
+		"absent":            {predicates: `[{"field": "xpath://g:name", "absent": true}]`, body: getGopherEnvelope, want: true},
+		"not xml":           {predicates: `[{"field": "xpath://g:id", "exists": true}]`, body: `{"id": 1}`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			imposter := imposter
+			require.NoError(t, json.Unmarshal([]byte(tc.predicates), &imposter.Request.Predicates))
+			require.NoError(t, imposter.Request.validate())
+
+			req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(tc.body))
+			assert.Equal(t, tc.want, MatcherByPredicates(imposter)(req, nil))
+		})
+	}
+}
@@ -0,0 +1,1131 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antchfx/xpath"
+)
+
+const (
+	xsdNamespace         = "http://www.w3.org/2001/XMLSchema"
+	xsdInstanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+	// unbounded is the maxOccurs of the particles that can be repeated without limit
+	unbounded = -1
+)
+
+var errUnsupportedXSD = errors.New("unsupported xsd")
+
+// xsdName is the qualified name of an element, an attribute or a type
+type xsdName struct {
+	space string
+	local string
+}
+
+func (n xsdName) String() string {
+	if n.space == "" {
+		return n.local
+	}
+	return "{" + n.space + "}" + n.local
+}
+
+// xsdSchema is a compiled XSD. It supports the subset of XSD used to describe the messages of the services:
+// the elements, the complex types with sequence, choice, all, any, attributes, simple and complex content
+// extensions, and the simple types with restrictions, lists and unions of the built-in types. The schemas
+// can include or import other schema files, relative to the schema file. The rest of declarations, and the
+// attributes that change how the documents are validated, like substitutionGroup, abstract, nillable, fixed
+// or the qualified attributes, are rejected when the schema is compiled
+type xsdSchema struct {
+	elements map[xsdName]*xsdElement
+}
+
+type xsdElement struct {
+	name xsdName
+	typ  *xsdType
+}
+
+type xsdAttribute struct {
+	name     xsdName
+	typ      *xsdType
+	required bool
+}
+
+type xsdType struct {
+	name xsdName
+	any  bool
+
+	// simple types
+	simple  bool
+	builtin string
+	base    *xsdType
+	facets  xsdFacets
+	list    *xsdType
+	union   []*xsdType
+
+	// complex types, extending the content of the base type, if any
+	particle *xsdParticle
+	attrs    []*xsdAttribute
+	anyAttr  bool
+	mixed    bool
+	text     *xsdType
+}
+
+type xsdFacets struct {
+	enumeration    []string
+	patterns       []*regexp.Regexp
+	length         *int
+	minLength      *int
+	maxLength      *int
+	minInclusive   *big.Float
+	maxInclusive   *big.Float
+	minExclusive   *big.Float
+	maxExclusive   *big.Float
+	totalDigits    *int
+	fractionDigits *int
+}
+
+type xsdParticleKind int
+
+const (
+	xsdParticleElement xsdParticleKind = iota
+	xsdParticleSequence
+	xsdParticleChoice
+	xsdParticleAll
+	xsdParticleAny
+)
+
+type xsdParticle struct {
+	kind     xsdParticleKind
+	element  *xsdElement
+	children []*xsdParticle
+	min      int
+	max      int
+}
+
+// xsdCompiler loads the schema files, resolving the references to the types and elements once all of them are loaded
+type xsdCompiler struct {
+	elements map[xsdName]*xsdElement
+	types    map[xsdName]*xsdType
+	files    []string
+	loaded   map[string]bool
+	resolves []func() error
+}
+
+// compileXSD compiles the schema file and the files it includes or imports, that are returned as its references
+func compileXSD(path string) (*xsdSchema, []string, error) {
+	c := &xsdCompiler{
+		elements: make(map[xsdName]*xsdElement),
+		types:    make(map[xsdName]*xsdType),
+		loaded:   make(map[string]bool),
+	}
+	if err := c.load(path, nil); err != nil {
+		return nil, c.files, err
+	}
+
+	for _, resolve := range c.resolves {
+		if err := resolve(); err != nil {
+			return nil, c.files, err
+		}
+	}
+	return &xsdSchema{elements: c.elements}, c.files, nil
+}
+
+// load compiles the schema file, the included ones must have the target namespace of the schema including them
+func (c *xsdCompiler) load(path string, namespace *string) error {
+	key := cacheKey(path)
+	if c.loaded[key] {
+		return nil
+	}
+	c.loaded[key] = true
+	if len(c.loaded) > 1 {
+		c.files = append(c.files, key)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseXML(data)
+	if err != nil {
+		return fmt.Errorf("%w: the schema %s is not xml", err, path)
+	}
+
+	schema := doc.firstElement()
+	if schema.space != xsdNamespace || schema.local != "schema" {
+		return fmt.Errorf("%w: the root element of %s is not a schema", errUnsupportedXSD, path)
+	}
+
+	d := xsdDocument{compiler: c, path: path}
+	d.targetNamespace, _ = schema.attr("", "targetNamespace")
+	if namespace != nil && d.targetNamespace != *namespace {
+		return fmt.Errorf("%w: the included schema %s must have the target namespace %q", errUnsupportedXSD, path, *namespace)
+	}
+	elementForm, _ := schema.attr("", "elementFormDefault")
+	d.qualified = elementForm == "qualified"
+	if attrValue(schema, "attributeFormDefault") == "qualified" {
+		return fmt.Errorf("%w: the qualified attributes of %s are not supported", errUnsupportedXSD, path)
+	}
+
+	for _, el := range schema.elements() {
+		if err := d.topLevel(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xsdDocument compiles the declarations of one of the schema files
+type xsdDocument struct {
+	compiler        *xsdCompiler
+	path            string
+	targetNamespace string
+	qualified       bool
+}
+
+func (d xsdDocument) topLevel(el *xmlNode) error {
+	if el.space != xsdNamespace {
+		return nil
+	}
+
+	name, _ := el.attr("", "name")
+	qname := xsdName{space: d.targetNamespace, local: name}
+	switch el.local {
+	case "annotation":
+	case "include", "import":
+		location, ok := el.attr("", "schemaLocation")
+		if !ok {
+			return nil
+		}
+		if u, err := url.Parse(location); err != nil || u.Scheme != "" {
+			return fmt.Errorf("%w: only the local schema files can be included, not %s", errUnsupportedXSD, location)
+		}
+		var namespace *string
+		if el.local == "include" {
+			namespace = &d.targetNamespace
+		}
+		return d.compiler.load(filepath.Join(filepath.Dir(d.path), filepath.FromSlash(location)), namespace)
+	case "element":
+		decl, err := d.element(el, qname)
+		if err != nil {
+			return err
+		}
+		d.compiler.elements[qname] = decl
+	case "complexType":
+		t, err := d.complexType(el)
+		if err != nil {
+			return err
+		}
+		t.name = qname
+		d.compiler.types[qname] = t
+	case "simpleType":
+		t, err := d.simpleType(el)
+		if err != nil {
+			return err
+		}
+		t.name = qname
+		d.compiler.types[qname] = t
+	default:
+		return fmt.Errorf("%w: xs:%s is not supported", errUnsupportedXSD, el.local)
+	}
+	return nil
+}
+
+// element compiles the declaration of an element, its type is either declared inline or resolved by name
+func (d xsdDocument) element(el *xmlNode, name xsdName) (*xsdElement, error) {
+	if err := unsupportedAttrs(el, "substitutionGroup", "abstract", "nillable", "fixed"); err != nil {
+		return nil, err
+	}
+
+	decl := &xsdElement{name: name}
+	for _, child := range el.elements() {
+		var err error
+		switch {
+		case child.space != xsdNamespace || child.local == "annotation":
+			continue
+		case child.local == "complexType":
+			decl.typ, err = d.complexType(child)
+		case child.local == "simpleType":
+			decl.typ, err = d.simpleType(child)
+		default:
+			err = fmt.Errorf("%w: xs:%s is not supported on the elements", errUnsupportedXSD, child.local)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if decl.typ != nil {
+		return decl, nil
+	}
+
+	typeName, ok := el.attr("", "type")
+	if !ok {
+		decl.typ = &xsdType{any: true}
+		return decl, nil
+	}
+	return decl, d.resolveType(el, typeName, func(t *xsdType) { decl.typ = t })
+}
+
+func (d xsdDocument) complexType(el *xmlNode) (*xsdType, error) {
+	if err := unsupportedAttrs(el, "abstract"); err != nil {
+		return nil, err
+	}
+
+	t := &xsdType{mixed: attrValue(el, "mixed") == "true"}
+	return t, d.complexContent(el, t)
+}
+
+// complexContent compiles the particle and attributes of a complex type, or of the extension of a base type
+func (d xsdDocument) complexContent(el *xmlNode, t *xsdType) error {
+	for _, child := range el.elements() {
+		if child.space != xsdNamespace {
+			continue
+		}
+
+		switch child.local {
+		case "annotation":
+		case "sequence", "choice", "all":
+			p, err := d.particle(child)
+			if err != nil {
+				return err
+			}
+			t.particle = p
+		case "attribute":
+			attr, err := d.attribute(child)
+			if err != nil {
+				return err
+			}
+			t.attrs = append(t.attrs, attr)
+		case "anyAttribute":
+			t.anyAttr = true
+		case "simpleContent", "complexContent":
+			ext := child.firstElement()
+			if ext == nil || ext.space != xsdNamespace || (ext.local != "extension" && ext.local != "restriction") {
+				return fmt.Errorf("%w: the %s must have an extension or a restriction", errUnsupportedXSD, child.local)
+			}
+			// a restriction of a complex content declares again all the content of the type, instead of extending the base
+			if child.local == "simpleContent" || ext.local == "extension" {
+				if err := d.resolveType(ext, attrValue(ext, "base"), func(base *xsdType) { t.base = base }); err != nil {
+					return err
+				}
+			}
+			if child.local == "simpleContent" {
+				t.text = &xsdType{simple: true}
+				d.compiler.resolves = append(d.compiler.resolves, func() error {
+					t.text.base = t.base.textType()
+					if t.text.base == nil {
+						return fmt.Errorf("%w: the simple content of %s must extend a simple type", errUnsupportedXSD, t.name)
+					}
+					return nil
+				})
+				if ext.local == "restriction" {
+					if err := d.facets(ext, &t.text.facets); err != nil {
+						return err
+					}
+				}
+			}
+			if err := d.complexContent(ext, t); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: xs:%s is not supported on the complex types", errUnsupportedXSD, child.local)
+		}
+	}
+	return nil
+}
+
+func (d xsdDocument) particle(el *xmlNode) (*xsdParticle, error) {
+	p := &xsdParticle{}
+	var err error
+	if p.min, p.max, err = occurs(el); err != nil {
+		return nil, err
+	}
+
+	switch el.local {
+	case "element":
+		p.kind = xsdParticleElement
+		if ref, ok := el.attr("", "ref"); ok {
+			name, err := resolveQName(el, ref)
+			if err != nil {
+				return nil, err
+			}
+			d.compiler.resolves = append(d.compiler.resolves, func() error {
+				if p.element = d.compiler.elements[name]; p.element == nil {
+					return fmt.Errorf("%w: the element %s is not declared", errUnsupportedXSD, name)
+				}
+				return nil
+			})
+			return p, nil
+		}
+
+		name := xsdName{local: attrValue(el, "name")}
+		if form, ok := el.attr("", "form"); (ok && form == "qualified") || (!ok && d.qualified) {
+			name.space = d.targetNamespace
+		}
+		p.element, err = d.element(el, name)
+		return p, err
+	case "any":
+		p.kind = xsdParticleAny
+		return p, nil
+	case "sequence":
+		p.kind = xsdParticleSequence
+	case "choice":
+		p.kind = xsdParticleChoice
+	case "all":
+		p.kind = xsdParticleAll
+	default:
+		return nil, fmt.Errorf("%w: xs:%s is not supported on the content of the types", errUnsupportedXSD, el.local)
+	}
+
+	for _, child := range el.elements() {
+		if child.space != xsdNamespace || child.local == "annotation" {
+			continue
+		}
+		c, err := d.particle(child)
+		if err != nil {
+			return nil, err
+		}
+		if p.kind == xsdParticleAll && (c.kind != xsdParticleElement || c.max == unbounded || c.max > 1) {
+			return nil, fmt.Errorf("%w: xs:all can only have elements that appear once", errUnsupportedXSD)
+		}
+		p.children = append(p.children, c)
+	}
+	return p, nil
+}
+
+func (d xsdDocument) attribute(el *xmlNode) (*xsdAttribute, error) {
+	if _, ok := el.attr("", "ref"); ok {
+		return nil, fmt.Errorf("%w: the references to attributes are not supported", errUnsupportedXSD)
+	}
+
+	if err := unsupportedAttrs(el, "fixed"); err != nil {
+		return nil, err
+	}
+	if attrValue(el, "form") == "qualified" {
+		return nil, fmt.Errorf("%w: the qualified attributes are not supported", errUnsupportedXSD)
+	}
+
+	attr := &xsdAttribute{name: xsdName{local: attrValue(el, "name")}, required: attrValue(el, "use") == "required"}
+	if simple := el.firstElement(); simple != nil && simple.space == xsdNamespace && simple.local == "simpleType" {
+		var err error
+		attr.typ, err = d.simpleType(simple)
+		return attr, err
+	}
+
+	typeName, ok := el.attr("", "type")
+	if !ok {
+		attr.typ = &xsdType{simple: true, builtin: "anySimpleType"}
+		return attr, nil
+	}
+	return attr, d.resolveType(el, typeName, func(t *xsdType) { attr.typ = t })
+}
+
+func (d xsdDocument) simpleType(el *xmlNode) (*xsdType, error) {
+	t := &xsdType{simple: true}
+	for _, child := range el.elements() {
+		if child.space != xsdNamespace || child.local == "annotation" {
+			continue
+		}
+
+		switch child.local {
+		case "restriction":
+			if base, ok := child.attr("", "base"); ok {
+				if err := d.resolveType(child, base, func(b *xsdType) { t.base = b }); err != nil {
+					return nil, err
+				}
+			} else if inline := child.firstElement(); inline != nil && inline.local == "simpleType" {
+				base, err := d.simpleType(inline)
+				if err != nil {
+					return nil, err
+				}
+				t.base = base
+			}
+			if err := d.facets(child, &t.facets); err != nil {
+				return nil, err
+			}
+		case "list":
+			t.list = &xsdType{simple: true}
+			if item, ok := child.attr("", "itemType"); ok {
+				if err := d.resolveType(child, item, func(i *xsdType) { t.list = i }); err != nil {
+					return nil, err
+				}
+			} else if inline := child.firstElement(); inline != nil && inline.local == "simpleType" {
+				item, err := d.simpleType(inline)
+				if err != nil {
+					return nil, err
+				}
+				t.list = item
+			}
+		case "union":
+			for _, member := range strings.Fields(attrValue(child, "memberTypes")) {
+				idx := len(t.union)
+				t.union = append(t.union, nil)
+				if err := d.resolveType(child, member, func(m *xsdType) { t.union[idx] = m }); err != nil {
+					return nil, err
+				}
+			}
+			for _, inline := range child.elements() {
+				if inline.space == xsdNamespace && inline.local == "simpleType" {
+					member, err := d.simpleType(inline)
+					if err != nil {
+						return nil, err
+					}
+					t.union = append(t.union, member)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: xs:%s is not supported on the simple types", errUnsupportedXSD, child.local)
+		}
+	}
+	return t, nil
+}
+
+func (d xsdDocument) facets(el *xmlNode, f *xsdFacets) error {
+	for _, facet := range el.elements() {
+		if facet.space != xsdNamespace {
+			continue
+		}
+
+		value := attrValue(facet, "value")
+		var err error
+		switch facet.local {
+		case "enumeration":
+			f.enumeration = append(f.enumeration, value)
+		case "pattern":
+			var re *regexp.Regexp
+			if re, err = regexp.Compile(`^(?:` + value + `)$`); err == nil {
+				f.patterns = append(f.patterns, re)
+			}
+		case "length":
+			f.length, err = intFacet(value)
+		case "minLength":
+			f.minLength, err = intFacet(value)
+		case "maxLength":
+			f.maxLength, err = intFacet(value)
+		case "totalDigits":
+			f.totalDigits, err = intFacet(value)
+		case "fractionDigits":
+			f.fractionDigits, err = intFacet(value)
+		case "minInclusive":
+			f.minInclusive, err = numberFacet(value)
+		case "maxInclusive":
+			f.maxInclusive, err = numberFacet(value)
+		case "minExclusive":
+			f.minExclusive, err = numberFacet(value)
+		case "maxExclusive":
+			f.maxExclusive, err = numberFacet(value)
+		case "annotation", "whiteSpace", "simpleType", "attribute", "anyAttribute", "sequence", "choice", "all":
+		default:
+			return fmt.Errorf("%w: the facet xs:%s is not supported", errUnsupportedXSD, facet.local)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: invalid facet xs:%s %q: %v", errUnsupportedXSD, facet.local, value, err)
+		}
+	}
+	return nil
+}
+
+// resolveType resolves the type by its name, once all the files are loaded
+func (d xsdDocument) resolveType(el *xmlNode, typeName string, set func(*xsdType)) error {
+	name, err := resolveQName(el, typeName)
+	if err != nil {
+		return err
+	}
+
+	if name.space == xsdNamespace {
+		t, err := builtinType(name.local)
+		if err != nil {
+			return err
+		}
+		set(t)
+		return nil
+	}
+
+	d.compiler.resolves = append(d.compiler.resolves, func() error {
+		t, ok := d.compiler.types[name]
+		if !ok {
+			return fmt.Errorf("%w: the type %s is not declared", errUnsupportedXSD, name)
+		}
+		set(t)
+		return nil
+	})
+	return nil
+}
+
+// resolveQName resolves a qualified name, like tns:Gopher, with the namespaces declared on the schema
+func resolveQName(el *xmlNode, qname string) (xsdName, error) {
+	prefix, local, ok := strings.Cut(qname, ":")
+	if !ok {
+		prefix, local = "", qname
+	}
+
+	space, ok := el.namespace(prefix)
+	if !ok {
+		return xsdName{}, fmt.Errorf("%w: undeclared namespace prefix %q", errUnsupportedXSD, prefix)
+	}
+	return xsdName{space: space, local: local}, nil
+}
+
+// unsupportedAttrs rejects the attributes of the declaration that change how the documents are validated,
+// unless they're set to false
+func unsupportedAttrs(el *xmlNode, names ...string) error {
+	for _, name := range names {
+		if v, ok := el.attr("", name); ok && v != "false" {
+			return fmt.Errorf("%w: the attribute %s of xs:%s is not supported", errUnsupportedXSD, name, el.local)
+		}
+	}
+	return nil
+}
+
+func attrValue(el *xmlNode, name string) string {
+	v, _ := el.attr("", name)
+	return v
+}
+
+func occurs(el *xmlNode) (int, int, error) {
+	min, max := 1, 1
+	if v, ok := el.attr("", "minOccurs"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("%w: invalid minOccurs %q", errUnsupportedXSD, v)
+		}
+		min = n
+	}
+	if v, ok := el.attr("", "maxOccurs"); ok {
+		if v == "unbounded" {
+			return min, unbounded, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("%w: invalid maxOccurs %q", errUnsupportedXSD, v)
+		}
+		max = n
+	}
+	return min, max, nil
+}
+
+func intFacet(value string) (*int, error) {
+	n, err := strconv.Atoi(value)
+	return &n, err
+}
+
+func numberFacet(value string) (*big.Float, error) {
+	f, ok := new(big.Float).SetString(value)
+	if !ok {
+		return nil, errors.New("it's not a number")
+	}
+	return f, nil
+}
+
+// textType returns the simple type of the text of the elements of the type
+func (t *xsdType) textType() *xsdType {
+	switch {
+	case t == nil:
+		return nil
+	case t.simple:
+		return t
+	case t.text != nil:
+		return t.text
+	}
+	return t.base.textType()
+}
+
+var (
+	integerPattern  = regexp.MustCompile(`^[+-]?\d+$`)
+	decimalPattern  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	datePattern     = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`)
+	timePattern     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	dateTimePattern = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	durationPattern = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+
+	// integerRanges are the bounds of the built-in integer types, nil when unbounded
+	integerRanges = map[string][2]*big.Int{
+		"integer":            {nil, nil},
+		"long":               {big.NewInt(-1 << 63), big.NewInt(1<<63 - 1)},
+		"int":                {big.NewInt(-1 << 31), big.NewInt(1<<31 - 1)},
+		"short":              {big.NewInt(-1 << 15), big.NewInt(1<<15 - 1)},
+		"byte":               {big.NewInt(-1 << 7), big.NewInt(1<<7 - 1)},
+		"nonNegativeInteger": {big.NewInt(0), nil},
+		"positiveInteger":    {big.NewInt(1), nil},
+		"nonPositiveInteger": {nil, big.NewInt(0)},
+		"negativeInteger":    {nil, big.NewInt(-1)},
+		"unsignedLong":       {big.NewInt(0), new(big.Int).SetUint64(1<<64 - 1)},
+		"unsignedInt":        {big.NewInt(0), big.NewInt(1<<32 - 1)},
+		"unsignedShort":      {big.NewInt(0), big.NewInt(1<<16 - 1)},
+		"unsignedByte":       {big.NewInt(0), big.NewInt(1<<8 - 1)},
+	}
+
+	// stringTypes are the built-in types whose values aren't checked
+	stringTypes = map[string]bool{
+		"anySimpleType": true, "string": true, "normalizedString": true, "token": true, "language": true,
+		"Name": true, "NCName": true, "NMTOKEN": true, "NMTOKENS": true, "ID": true, "IDREF": true, "IDREFS": true,
+		"ENTITY": true, "ENTITIES": true, "QName": true, "NOTATION": true, "anyURI": true,
+		"gYear": true, "gYearMonth": true, "gMonth": true, "gMonthDay": true, "gDay": true,
+	}
+)
+
+func builtinType(name string) (*xsdType, error) {
+	if name == "anyType" {
+		return &xsdType{any: true}, nil
+	}
+
+	_, integer := integerRanges[name]
+	switch {
+	case integer, stringTypes[name]:
+	case name == "boolean", name == "decimal", name == "float", name == "double", name == "date", name == "time",
+		name == "dateTime", name == "duration", name == "base64Binary", name == "hexBinary":
+	default:
+		return nil, fmt.Errorf("%w: the type xs:%s is not supported", errUnsupportedXSD, name)
+	}
+	return &xsdType{simple: true, builtin: name}, nil
+}
+
+// validateXML validates the document, or the content of the body if it's a SOAP envelope, against the schema
+func (s *xsdSchema) validateXML(doc *xmlNode) []schemaError {
+	roots := []*xmlNode{doc.firstElement()}
+	if body := soapBody(doc); body != nil {
+		roots = body.elements()
+	}
+
+	v := &xsdValidator{}
+	for _, root := range roots {
+		decl, ok := s.elements[xsdName{space: root.space, local: root.local}]
+		if !ok {
+			v.fail(root.path(), "unexpected_element", fmt.Sprintf("the element %s is not declared on the schema", xsdName{root.space, root.local}))
+			continue
+		}
+		v.element(root, decl)
+	}
+	return v.errors
+}
+
+type xsdValidator struct {
+	errors []schemaError
+}
+
+func (v *xsdValidator) fail(field, typ, description string) {
+	v.errors = append(v.errors, schemaError{Field: field, Type: typ, Description: description})
+}
+
+func (v *xsdValidator) element(el *xmlNode, decl *xsdElement) {
+	t := decl.typ
+	if t.any {
+		return
+	}
+
+	if t.simple {
+		if len(el.elements()) > 0 {
+			v.fail(el.path(), "unexpected_element", fmt.Sprintf("the element %s can only have text", el.local))
+			return
+		}
+		v.value(el.path(), t, el.value())
+		return
+	}
+
+	v.attributes(el, t)
+
+	children := el.elements()
+	if text := t.textType(); text != nil {
+		if len(children) > 0 {
+			v.fail(el.path(), "unexpected_element", fmt.Sprintf("the element %s can only have text", el.local))
+			return
+		}
+		v.value(el.path(), text, el.value())
+		return
+	}
+
+	if !t.isMixed() {
+		for _, child := range el.children {
+			if child.typ == xpath.TextNode && strings.TrimSpace(child.text) != "" {
+				v.fail(el.path(), "unexpected_text", fmt.Sprintf("the element %s can't have text", el.local))
+				break
+			}
+		}
+	}
+
+	m := &xsdMatcher{children: children}
+	matched := m.match(t.content(), 0, func(end int) bool {
+		if end == len(children) {
+			return true
+		}
+		if end > m.failure.pos {
+			m.failure.pos, m.failure.expected = end, ""
+		}
+		return false
+	})
+	if !matched {
+		switch {
+		case m.failure.pos >= len(children):
+			v.fail(el.path(), "required", fmt.Sprintf("the element %s is required", m.failure.expected))
+		case m.failure.expected == "":
+			v.fail(children[m.failure.pos].path(), "unexpected_element", fmt.Sprintf("the element %s is not expected", children[m.failure.pos].local))
+		default:
+			v.fail(children[m.failure.pos].path(), "unexpected_element", fmt.Sprintf("the element %s is not expected, expected %s", children[m.failure.pos].local, m.failure.expected))
+		}
+		return
+	}
+
+	for _, a := range m.assigned {
+		if a.decl == nil {
+			continue
+		}
+		v.element(a.node, a.decl)
+	}
+}
+
+func (v *xsdValidator) attributes(el *xmlNode, t *xsdType) {
+	declared := t.attributes()
+	for _, attr := range declared {
+		value, ok := el.attr(attr.name.space, attr.name.local)
+		if !ok {
+			if attr.required {
+				v.fail(el.path()+"/@"+attr.name.local, "required", fmt.Sprintf("the attribute %s is required", attr.name.local))
+			}
+			continue
+		}
+		v.value(el.path()+"/@"+attr.name.local, attr.typ, value)
+	}
+
+	if t.hasAnyAttribute() {
+		return
+	}
+	for _, a := range el.attrs {
+		if a.space == xsdInstanceNamespace {
+			continue
+		}
+		allowed := false
+		for _, attr := range declared {
+			allowed = allowed || (attr.name.space == a.space && attr.name.local == a.local)
+		}
+		if !allowed {
+			v.fail(el.path()+"/@"+a.local, "unexpected_attribute", fmt.Sprintf("the attribute %s is not allowed", a.local))
+		}
+	}
+}
+
+func (v *xsdValidator) value(field string, t *xsdType, value string) {
+	if err := t.validateValue(value); err != nil {
+		v.fail(field, "invalid_value", err.Error())
+	}
+}
+
+// content returns the particle of the type, after the particle of its base type, if it extends one
+func (t *xsdType) content() *xsdParticle {
+	if t.base == nil || t.base.simple || t.base.any {
+		return t.particle
+	}
+
+	base := t.base.content()
+	switch {
+	case base == nil:
+		return t.particle
+	case t.particle == nil:
+		return base
+	}
+	return &xsdParticle{kind: xsdParticleSequence, children: []*xsdParticle{base, t.particle}, min: 1, max: 1}
+}
+
+func (t *xsdType) attributes() []*xsdAttribute {
+	if t.base == nil || t.base.simple {
+		return t.attrs
+	}
+	return append(t.base.attributes(), t.attrs...)
+}
+
+func (t *xsdType) hasAnyAttribute() bool {
+	return t.anyAttr || (t.base != nil && t.base.hasAnyAttribute()) || (t.base != nil && t.base.any)
+}
+
+func (t *xsdType) isMixed() bool {
+	return t.mixed || (t.base != nil && !t.base.simple && t.base.isMixed())
+}
+
+// validateValue validates the text of an element or an attribute against a simple type
+func (t *xsdType) validateValue(value string) error {
+	switch {
+	case t.any:
+		return nil
+	case t.list != nil:
+		for _, item := range strings.Fields(value) {
+			if err := t.list.validateValue(item); err != nil {
+				return err
+			}
+		}
+		return t.facets.validate(value, len(strings.Fields(value)))
+	case len(t.union) > 0:
+		for _, member := range t.union {
+			if member.validateValue(value) == nil {
+				return t.facets.validate(value, utf8.RuneCountInString(value))
+			}
+		}
+		return fmt.Errorf("the value %q doesn't match any of the types of the union", value)
+	case t.builtin != "":
+		if !stringTypes[t.builtin] {
+			value = strings.TrimSpace(value)
+		}
+		if err := validateBuiltin(t.builtin, value); err != nil {
+			return err
+		}
+	case t.base != nil:
+		if err := t.base.validateValue(value); err != nil {
+			return err
+		}
+		if b := t.base.builtinBase(); b != "" && !stringTypes[b] {
+			value = strings.TrimSpace(value)
+		}
+	}
+	return t.facets.validate(value, utf8.RuneCountInString(value))
+}
+
+// builtinBase returns the built-in type the type is derived from
+func (t *xsdType) builtinBase() string {
+	for ; t != nil; t = t.base {
+		if t.builtin != "" {
+			return t.builtin
+		}
+	}
+	return ""
+}
+
+func (f xsdFacets) validate(value string, length int) error {
+	if len(f.enumeration) > 0 {
+		found := false
+		for _, e := range f.enumeration {
+			found = found || e == value
+		}
+		if !found {
+			return fmt.Errorf("the value %q must be one of the following: %s", value, strings.Join(f.enumeration, ", "))
+		}
+	}
+
+	for _, re := range f.patterns {
+		if !re.MatchString(value) {
+			return fmt.Errorf("the value %q doesn't match the pattern %s", value, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+		}
+	}
+
+	switch {
+	case f.length != nil && length != *f.length:
+		return fmt.Errorf("the length of %q must be %d", value, *f.length)
+	case f.minLength != nil && length < *f.minLength:
+		return fmt.Errorf("the length of %q must be at least %d", value, *f.minLength)
+	case f.maxLength != nil && length > *f.maxLength:
+		return fmt.Errorf("the length of %q must be at most %d", value, *f.maxLength)
+	}
+
+	if f.minInclusive == nil && f.maxInclusive == nil && f.minExclusive == nil && f.maxExclusive == nil && f.totalDigits == nil && f.fractionDigits == nil {
+		return nil
+	}
+
+	n, ok := new(big.Float).SetString(value)
+	if !ok {
+		return fmt.Errorf("the value %q is not a number", value)
+	}
+	switch {
+	case f.minInclusive != nil && n.Cmp(f.minInclusive) < 0:
+		return fmt.Errorf("the value %s must be greater than or equal to %s", value, f.minInclusive.String())
+	case f.maxInclusive != nil && n.Cmp(f.maxInclusive) > 0:
+		return fmt.Errorf("the value %s must be less than or equal to %s", value, f.maxInclusive.String())
+	case f.minExclusive != nil && n.Cmp(f.minExclusive) <= 0:
+		return fmt.Errorf("the value %s must be greater than %s", value, f.minExclusive.String())
+	case f.maxExclusive != nil && n.Cmp(f.maxExclusive) >= 0:
+		return fmt.Errorf("the value %s must be less than %s", value, f.maxExclusive.String())
+	}
+
+	digits := strings.TrimLeft(strings.TrimLeft(value, "+-"), "0")
+	integerPart, fractionPart, _ := strings.Cut(digits, ".")
+	fractionPart = strings.TrimRight(fractionPart, "0")
+	switch {
+	case f.totalDigits != nil && len(integerPart)+len(fractionPart) > *f.totalDigits:
+		return fmt.Errorf("the value %s must have at most %d digits", value, *f.totalDigits)
+	case f.fractionDigits != nil && len(fractionPart) > *f.fractionDigits:
+		return fmt.Errorf("the value %s must have at most %d fraction digits", value, *f.fractionDigits)
+	}
+	return nil
+}
+
+func validateBuiltin(builtin, value string) error {
+	if bounds, ok := integerRanges[builtin]; ok {
+		n, ok := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+		if !ok || !integerPattern.MatchString(value) {
+			return fmt.Errorf("the value %q is not a valid %s", value, builtin)
+		}
+		if (bounds[0] != nil && n.Cmp(bounds[0]) < 0) || (bounds[1] != nil && n.Cmp(bounds[1]) > 0) {
+			return fmt.Errorf("the value %s is out of the range of %s", value, builtin)
+		}
+		return nil
+	}
+
+	valid := true
+	switch builtin {
+	case "boolean":
+		valid = value == "true" || value == "false" || value == "1" || value == "0"
+	case "decimal":
+		valid = decimalPattern.MatchString(value)
+	case "float", "double":
+		_, err := strconv.ParseFloat(value, 64)
+		valid = err == nil || value == "INF" || value == "-INF" || value == "NaN"
+	case "date":
+		valid = datePattern.MatchString(value)
+	case "time":
+		valid = timePattern.MatchString(value)
+	case "dateTime":
+		valid = dateTimePattern.MatchString(value)
+	case "duration":
+		valid = durationPattern.MatchString(value) && value != "P" && value != "-P" && !strings.HasSuffix(value, "T")
+	case "base64Binary":
+		_, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		valid = err == nil
+	case "hexBinary":
+		_, err := hex.DecodeString(value)
+		valid = err == nil
+	}
+	if !valid {
+		return fmt.Errorf("the value %q is not a valid %s", value, builtin)
+	}
+	return nil
+}
+
+// xsdMatcher matches the child elements of an element against the particle of its type. It only
+// checks their names, the matched elements are validated against their declarations afterwards.
+// The occurrences of the particles and the branches of the choices are tried in order, the longest
+// first, going back to the next option when the rest of the content doesn't match
+type xsdMatcher struct {
+	children []*xmlNode
+	assigned []xsdAssignment
+	failure  struct {
+		pos      int
+		expected string
+	}
+}
+
+type xsdAssignment struct {
+	node *xmlNode
+	decl *xsdElement
+}
+
+// match matches the particle from the position pos, and then the rest of the content with next,
+// which receives the position after the matched children. The assignments of the matched children
+// are kept only when next succeeds
+func (m *xsdMatcher) match(p *xsdParticle, pos int, next func(int) bool) bool {
+	if p == nil {
+		return next(pos)
+	}
+	return m.occurrences(p, pos, 0, next)
+}
+
+// occurrences matches one more occurrence of the particle, after count of them, or the rest of the content
+func (m *xsdMatcher) occurrences(p *xsdParticle, pos, count int, next func(int) bool) bool {
+	if p.max == unbounded || count < p.max {
+		assigned := len(m.assigned)
+		matched := m.matchOnce(p, pos, func(end int) bool {
+			// the occurrences that don't match any element can't be repeated once the minimum is reached
+			if end == pos && count >= p.min {
+				return false
+			}
+			return m.occurrences(p, end, count+1, next)
+		})
+		if matched {
+			return true
+		}
+		m.assigned = m.assigned[:assigned]
+	}
+	return count >= p.min && next(pos)
+}
+
+func (m *xsdMatcher) matchOnce(p *xsdParticle, pos int, next func(int) bool) bool {
+	switch p.kind {
+	case xsdParticleElement:
+		if pos < len(m.children) && m.children[pos].local == p.element.name.local && m.children[pos].space == p.element.name.space {
+			return m.assign(m.children[pos], p.element, pos, next)
+		}
+		m.fail(pos, p.element.name.local)
+		return false
+	case xsdParticleAny:
+		if pos < len(m.children) {
+			return m.assign(m.children[pos], nil, pos, next)
+		}
+		m.fail(pos, "any element")
+		return false
+	case xsdParticleSequence:
+		return m.sequence(p.children, pos, next)
+	case xsdParticleChoice:
+		for _, child := range p.children {
+			assigned := len(m.assigned)
+			if m.match(child, pos, next) {
+				return true
+			}
+			m.assigned = m.assigned[:assigned]
+		}
+		return false
+	case xsdParticleAll:
+		// the elements of an all appear once, so each child element has only one possible particle
+		assigned := len(m.assigned)
+		used := make([]bool, len(p.children))
+		start := pos
+	next:
+		for pos < len(m.children) {
+			for i, child := range p.children {
+				if !used[i] && m.children[pos].local == child.element.name.local && m.children[pos].space == child.element.name.space {
+					m.assigned = append(m.assigned, xsdAssignment{node: m.children[pos], decl: child.element})
+					used[i] = true
+					pos++
+					continue next
+				}
+			}
+			break
+		}
+		for i, child := range p.children {
+			if !used[i] && child.min > 0 {
+				m.fail(pos, child.element.name.local)
+				m.assigned = m.assigned[:assigned]
+				return false
+			}
+		}
+		if next(pos) || (pos > start && p.min == 0 && next(start)) {
+			return true
+		}
+		m.assigned = m.assigned[:assigned]
+		return false
+	}
+	return false
+}
+
+// sequence matches the particles one after the other, and then the rest of the content
+func (m *xsdMatcher) sequence(particles []*xsdParticle, pos int, next func(int) bool) bool {
+	if len(particles) == 0 {
+		return next(pos)
+	}
+	return m.match(particles[0], pos, func(end int) bool {
+		return m.sequence(particles[1:], end, next)
+	})
+}
+
+// assign assigns the child element at the position to its declaration, if the rest of the content matches
+func (m *xsdMatcher) assign(node *xmlNode, decl *xsdElement, pos int, next func(int) bool) bool {
+	assigned := len(m.assigned)
+	m.assigned = append(m.assigned, xsdAssignment{node: node, decl: decl})
+	if next(pos + 1) {
+		return true
+	}
+	m.assigned = m.assigned[:assigned]
+	return false
+}
+
+// fail records the element expected at the position, keeping the furthest position reached
+func (m *xsdMatcher) fail(pos int, expected string) {
+	if pos >= m.failure.pos {
+		m.failure.pos, m.failure.expected = pos, expected
+	}
+}
@@ -0,0 +1,270 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gopherTypesXSD = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:gop="http://example.com/gophers"
+    targetNamespace="http://example.com/gophers" elementFormDefault="qualified">
+  <xs:simpleType name="color">
+    <xs:restriction base="xs:string">
+      <xs:enumeration value="purple"/>
+      <xs:enumeration value="blue"/>
+    </xs:restriction>
+  </xs:simpleType>
+</xs:schema>`
+
+const gopherXSD = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:gop="http://example.com/gophers"
+    targetNamespace="http://example.com/gophers" elementFormDefault="qualified">
+  <xs:include schemaLocation="types.xsd"/>
+  <xs:element name="CreateGopher">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="name" type="xs:string"/>
+        <xs:element name="color" type="gop:color"/>
+        <xs:element name="age" type="xs:positiveInteger" minOccurs="0"/>
+        <xs:element name="tag" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+      </xs:sequence>
+      <xs:attribute name="version" type="xs:int" use="required"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func writeGopherXSD(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "types.xsd"), []byte(gopherTypesXSD), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.xsd"), []byte(gopherXSD), 0o644))
+	return dir
+}
+
+func TestCompileXSD(t *testing.T) {
+	dir := writeGopherXSD(t)
+
+	schema, refs, err := compileXSD(filepath.Join(dir, "gopher.xsd"))
+	require.NoError(t, err)
+	assert.NotNil(t, schema)
+	assert.Equal(t, []string{cacheKey(filepath.Join(dir, "types.xsd"))}, refs)
+
+	unsupported := map[string]string{
+		"group":                `<xs:group name="gophers"><xs:sequence/></xs:group>`,
+		"attribute ref":        `<xs:element name="gopher"><xs:complexType><xs:attribute ref="gop:version"/></xs:complexType></xs:element>`,
+		"remote include":       `<xs:include schemaLocation="http://example.com/gophers.xsd"/>`,
+		"substitution group":   `<xs:element name="gopher"/><xs:element name="pet" substitutionGroup="gop:gopher"/>`,
+		"abstract element":     `<xs:element name="gopher" abstract="true"/>`,
+		"nillable element":     `<xs:element name="gopher" nillable="true"/>`,
+		"fixed element":        `<xs:element name="gopher" type="xs:string" fixed="Zebediah"/>`,
+		"abstract type":        `<xs:complexType name="gopher" abstract="true"/>`,
+		"fixed attribute":      `<xs:element name="gopher"><xs:complexType><xs:attribute name="version" type="xs:int" fixed="1"/></xs:complexType></xs:element>`,
+		"qualified attribute":  `<xs:element name="gopher"><xs:complexType><xs:attribute name="version" type="xs:int" form="qualified"/></xs:complexType></xs:element>`,
+		"repeated all element": `<xs:element name="gopher"><xs:complexType><xs:all><xs:element name="tag" maxOccurs="unbounded"/></xs:all></xs:complexType></xs:element>`,
+		"choice in all":        `<xs:element name="gopher"><xs:complexType><xs:all><xs:choice/></xs:all></xs:complexType></xs:element>`,
+	}
+	for name, decl := range unsupported {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.xsd")
+			require.NoError(t, os.WriteFile(path, []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:gop="http://example.com/gophers" targetNamespace="http://example.com/gophers">`+decl+`</xs:schema>`), 0o644))
+
+			_, _, err := compileXSD(path)
+			assert.True(t, errors.Is(err, errUnsupportedXSD), "unexpected error %v", err)
+		})
+	}
+
+	t.Run("include of another namespace", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "types.xsd"), []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"/>`), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gopher.xsd"), []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="http://example.com/gophers"><xs:include schemaLocation="types.xsd"/></xs:schema>`), 0o644))
+
+		_, _, err := compileXSD(filepath.Join(dir, "gopher.xsd"))
+		assert.True(t, errors.Is(err, errUnsupportedXSD), "unexpected error %v", err)
+	})
+}
+
+func TestXSDSchema_ValidateXML(t *testing.T) {
+	schema, _, err := compileXSD(filepath.Join(writeGopherXSD(t), "gopher.xsd"))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		document string
+		want     []schemaError
+	}{
+		"valid document": {
+			document: `<CreateGopher xmlns="http://example.com/gophers" version="1"><name>Zebediah</name><color>purple</color><tag>small</tag><tag>fast</tag></CreateGopher>`,
+		},
+		"valid envelope": {
+			document: `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:gop="http://example.com/gophers">
+  <soapenv:Body>
+    <gop:CreateGopher version="1">
+      <gop:name>Zebediah</gop:name>
+      <gop:color>blue</gop:color>
+      <gop:age>3</gop:age>
+    </gop:CreateGopher>
+  </soapenv:Body>
+</soapenv:Envelope>`,
+		},
+		"invalid values": {
+			document: `<CreateGopher xmlns="http://example.com/gophers" version="first"><name>Zebediah</name><color>green</color><age>-1</age></CreateGopher>`,
+			want: []schemaError{
+				{Field: "/CreateGopher/@version", Type: "invalid_value"},
+				{Field: "/CreateGopher/color", Type: "invalid_value"},
+				{Field: "/CreateGopher/age", Type: "invalid_value"},
+			},
+		},
+		"missing element and attribute": {
+			document: `<CreateGopher xmlns="http://example.com/gophers"><name>Zebediah</name></CreateGopher>`,
+			want: []schemaError{
+				{Field: "/CreateGopher/@version", Type: "required"},
+				{Field: "/CreateGopher", Type: "required"},
+			},
+		},
+		"unexpected element and attribute": {
+			document: `<CreateGopher xmlns="http://example.com/gophers" version="1" id="1"><name>Zebediah</name><color>blue</color><owner>Gopher</owner></CreateGopher>`,
+			want: []schemaError{
+				{Field: "/CreateGopher/@id", Type: "unexpected_attribute"},
+				{Field: "/CreateGopher/owner", Type: "unexpected_element"},
+			},
+		},
+		"unqualified elements": {
+			document: `<CreateGopher version="1"><name>Zebediah</name><color>blue</color></CreateGopher>`,
+			want:     []schemaError{{Field: "/CreateGopher", Type: "unexpected_element"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseXML([]byte(tc.document))
+			require.NoError(t, err)
+
+			errs := schema.validateXML(doc)
+			require.Len(t, errs, len(tc.want), "unexpected errors %v", errs)
+			for i, want := range tc.want {
+				assert.Equal(t, want.Field, errs[i].Field)
+				assert.Equal(t, want.Type, errs[i].Type)
+				assert.NotEmpty(t, errs[i].Description)
+			}
+		})
+	}
+}
+
+func TestXSDSchema_ValidateXML_ContentModels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "burrow.xsd")
+	require.NoError(t, os.WriteFile(path, []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="burrow">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="gopher" minOccurs="0"/>
+        <xs:element name="gopher"/>
+        <xs:choice>
+          <xs:element name="tunnel" maxOccurs="unbounded"/>
+          <xs:sequence>
+            <xs:element name="tunnel"/>
+            <xs:element name="exit"/>
+          </xs:sequence>
+        </xs:choice>
+        <xs:element name="entrance"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`), 0o644))
+
+	schema, _, err := compileXSD(path)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		document string
+		want     []schemaError
+	}{
+		"optional element followed by the same element": {
+			document: `<burrow><gopher/><tunnel/><entrance/></burrow>`,
+		},
+		"both elements": {
+			document: `<burrow><gopher/><gopher/><tunnel/><tunnel/><entrance/></burrow>`,
+		},
+		"second branch of the choice": {
+			document: `<burrow><gopher/><tunnel/><exit/><entrance/></burrow>`,
+		},
+		"missing element after the choice": {
+			document: `<burrow><gopher/><tunnel/><exit/></burrow>`,
+			want:     []schemaError{{Field: "/burrow", Type: "required"}},
+		},
+		"unexpected element": {
+			document: `<burrow><gopher/><gopher/><gopher/><tunnel/><entrance/></burrow>`,
+			want:     []schemaError{{Field: "/burrow/gopher", Type: "unexpected_element"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseXML([]byte(tc.document))
+			require.NoError(t, err)
+
+			errs := schema.validateXML(doc)
+			require.Len(t, errs, len(tc.want), "unexpected errors %v", errs)
+			for i, want := range tc.want {
+				assert.Equal(t, want.Field, errs[i].Field)
+				assert.Equal(t, want.Type, errs[i].Type)
+				assert.NotEmpty(t, errs[i].Description)
+			}
+		})
+	}
+}
+
+func TestImposterHandler_XSDErrors(t *testing.T) {
+	xsdFile := "gopher.xsd"
+	imposter := Imposter{
+		BasePath: writeGopherXSD(t),
+		Request:  Request{Method: http.MethodPost, Endpoint: "/soap", XSDFile: &xsdFile, SchemaErrorStatus: http.StatusBadRequest},
+		Response: Responses{{Status: http.StatusOK}},
+		files:    NewFileCache(),
+	}
+
+	testCases := map[string]struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"valid body": {
+			body:       `<CreateGopher xmlns="http://example.com/gophers" version="1"><name>Zebediah</name><color>blue</color></CreateGopher>`,
+			wantStatus: http.StatusOK,
+		},
+		"invalid body": {
+			body:       `<CreateGopher xmlns="http://example.com/gophers" version="1"><name>Zebediah</name><color>green</color></CreateGopher>`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors": [{"field": "/CreateGopher/color", "type": "invalid_value", "description": "the value \"green\" must be one of the following: purple, blue"}]}`,
+		},
+		"not xml": {
+			body:       `{"name": "Zebediah"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(tc.body))
+			req = req.WithContext(withMatchedSchemaErrors(req.Context()))
+			require.True(t, MatcherByXSD(imposter)(req, nil))
+
+			rec := httptest.NewRecorder()
+			ImposterHandler(imposter).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	imposter.Request.SchemaErrorStatus = 0
+	req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(`<CreateGopher xmlns="http://example.com/gophers"/>`))
+	assert.False(t, MatcherByXSD(imposter)(req, nil), "without schemaErrorStatus the invalid bodies don't match")
+}
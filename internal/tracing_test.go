@@ -0,0 +1,25 @@
+package killgrave
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	testCases := map[string]ConfigTracing{
+		"host and port":     {Endpoint: "localhost:4318", Insecure: true},
+		"url":               {Endpoint: "http://localhost:4318/v1/traces"},
+		"with service name": {Endpoint: "localhost:4318", ServiceName: "payments-mock"},
+	}
+
+	for name, cfg := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tp, err := NewTracerProvider(context.Background(), cfg)
+			require.NoError(t, err)
+			assert.NoError(t, tp.Shutdown(context.Background()))
+		})
+	}
+}
@@ -2,32 +2,41 @@ package killgrave
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"os"
 	"time"
 
 	"github.com/radovskyb/watcher"
 )
 
 // InitializeWatcher initialize a watcher to check for modification on all files
-// in the given path to watch
-func InitializeWatcher(pathToWatch string) (*watcher.Watcher, error) {
+// in the given paths to watch, the remote paths are ignored
+func InitializeWatcher(pathsToWatch ...string) (*watcher.Watcher, error) {
 	w := watcher.New()
 	w.SetMaxEvents(1)
 	w.FilterOps(watcher.Rename, watcher.Move, watcher.Create, watcher.Write)
 
-	if err := w.AddRecursive(pathToWatch); err != nil {
-		return nil, fmt.Errorf("%w: error trying to watch change on %s directory", err, pathToWatch)
+	for _, pathToWatch := range pathsToWatch {
+		if u, err := url.Parse(pathToWatch); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			continue
+		}
+
+		if err := w.AddRecursive(pathToWatch); err != nil {
+			return nil, fmt.Errorf("%w: error trying to watch change on %s directory", err, pathToWatch)
+		}
 	}
 	return w, nil
 }
 
 // AttachWatcher start the watcher, if any error was produced while the starting process the application would crash
 // you need to pass a function, this function is the function that will be executed when the watcher
-// receive any event the type of defined on the InitializeWatcher function
-func AttachWatcher(w *watcher.Watcher, fn func()) {
+// receive any event the type of defined on the InitializeWatcher function, with the event received
+func AttachWatcher(w *watcher.Watcher, fn func(watcher.Event)) {
 	go func() {
 		if err := w.Start(time.Millisecond * 100); err != nil {
-			log.Fatalln(err)
+			slog.Error("error starting the watcher", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -42,15 +51,15 @@ func CloseWatcher(w *watcher.Watcher) {
 	w.Close()
 }
 
-func readEventsFromWatcher(w *watcher.Watcher, fn func()) {
+func readEventsFromWatcher(w *watcher.Watcher, fn func(watcher.Event)) {
 	go func() {
 		for {
 			select {
 			case evt := <-w.Event:
-				log.Println("Modified file:", evt.Name())
-				fn()
+				slog.Info("modified file", "file", evt.Path)
+				fn(evt)
 			case err := <-w.Error:
-				log.Printf("Error checking file change: %+v", err)
+				slog.Error("error checking file change", "error", err)
 			case <-w.Closed:
 				return
 			}
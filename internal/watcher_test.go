@@ -22,9 +22,9 @@ func TestAttachWatcher(t *testing.T) {
 	tests := []struct {
 		name string
 		w    *watcher.Watcher
-		fn   func()
+		fn   func(watcher.Event)
 	}{
-		{"attach watcher and process", watcher.New(), func() { spy = true }},
+		{"attach watcher and process", watcher.New(), func(watcher.Event) { spy = true }},
 	}
 	for _, tt := range tests {
 